@@ -1,8 +1,10 @@
 package canopen
 
 const (
-	MessageTypeNMT       uint16 = 0x000
-	MessageTypeSync      uint16 = 0x080
+	MessageTypeNMT  uint16 = 0x000
+	MessageTypeSync uint16 = 0x080
+	// MessageTypeEMCY represents the type of emergency messages, it shares the type with SYNC (node id 0)
+	MessageTypeEMCY      uint16 = 0x080
 	MessageTypeTimestamp uint16 = 0x100
 	MessageTypeTPDO1     uint16 = 0x180
 	MessageTypeRPDO1     uint16 = 0x200
@@ -1,6 +1,7 @@
 package canopen
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"github.com/FabianPetersen/can"
@@ -10,6 +11,33 @@ import (
 
 var Lock = maplock.New()
 
+// LockContext acquires the lock of key like Lock.Lock, but gives up when ctx is done.
+// The returned error wraps ctx.Err() and the lock must only be released if no error was returned.
+func LockContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("waiting for lock %s: %w", key, err)
+	}
+
+	locked := make(chan struct{})
+	go func() {
+		Lock.Lock(key)
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		return nil
+	case <-ctx.Done():
+		// Release the lock as soon as it is acquired
+		go func() {
+			<-locked
+			Lock.Unlock(key)
+		}()
+
+		return fmt.Errorf("waiting for lock %s: %w", key, ctx.Err())
+	}
+}
+
 type SDOAbortCode int
 
 const (
@@ -119,6 +147,15 @@ type TransferAbort struct {
 	AbortCode []uint8
 }
 
+// Code returns the abort code of the transfer.
+func (e TransferAbort) Code() SDOAbortCode {
+	if len(e.AbortCode) == 4 {
+		return SDOAbortCode(binary.LittleEndian.Uint32(e.AbortCode))
+	}
+
+	return SDO_ERR_GENERAL
+}
+
 func (e TransferAbort) Error() string {
 	if len(e.AbortCode) == 4 {
 		code := binary.LittleEndian.Uint32(e.AbortCode)
@@ -174,13 +211,37 @@ func (c *Client) Do(req *Request) (*Response, error) {
 // DoMinDuration sends a request and waits for a response.
 // If the response frame doesn't arrive on time, an error is returned.
 func (c *Client) DoMinDuration(req *Request, min time.Duration) (*Response, error) {
-	rch := can.Wait(c.Bus, req.ResponseID, c.Timeout)
+	return c.DoMinDurationContext(context.Background(), req, min)
+}
+
+// DoContext sends a request and waits for a response until the timeout passed or ctx is done.
+// The returned error wraps context.DeadlineExceeded or context.Canceled.
+func (c *Client) DoContext(ctx context.Context, req *Request) (*Response, error) {
+	return c.DoMinDurationContext(ctx, req, 10*time.Millisecond)
+}
+
+// DoMinDurationContext sends a request and waits for a response until the timeout passed or ctx is done.
+// The returned error wraps context.DeadlineExceeded or context.Canceled.
+func (c *Client) DoMinDurationContext(ctx context.Context, req *Request, min time.Duration) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("request %X: %w", req.Frame.CobID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	// Subscribe before publishing to not miss a fast response
+	sub := Subscribe(c.Bus, uint16(req.ResponseID), 1)
+	defer sub.Close()
 
 	if err := c.Bus.PublishMinDuration(req.Frame.CANFrame(), min); err != nil {
 		return nil, err
 	}
 
-	resp := <-rch
+	frame, err := sub.ReceiveContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return &Response{CANopenFrame(resp.Frame), req}, resp.Err
+	return &Response{frame, req}, nil
 }
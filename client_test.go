@@ -0,0 +1,79 @@
+package canopen
+
+import (
+	"context"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestClientContext(t *testing.T) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	go serverBus.ConnectAndPublish()
+	defer clientBus.Disconnect()
+
+	// The server answers every request of node 1
+	serverBus.SubscribeFunc(func(frm can.Frame) {
+		if frm.ID == uint32(MessageTypeRSDO+1) {
+			_ = serverBus.PublishMinDuration(NewFrame(MessageTypeTSDO+1, frm.Data[:]).CANFrame(), 0)
+		}
+	})
+
+	client := &Client{Bus: clientBus, Timeout: time.Second}
+	request := NewRequest(NewFrame(MessageTypeRSDO+1, []byte{0x40, 0x00, 0x10, 0x00, 0, 0, 0, 0}), uint32(MessageTypeTSDO+1))
+	if response, err := client.DoContext(context.Background(), request); err != nil || response.Frame.CobID != MessageTypeTSDO+1 {
+		t.Log("Unexpected response", response, err)
+		t.FailNow()
+	}
+
+	// Node 2 never answers
+	request = NewRequest(NewFrame(MessageTypeRSDO+2, []byte{0x40, 0x00, 0x10, 0x00, 0, 0, 0, 0}), uint32(MessageTypeTSDO+2))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.DoContext(ctx, request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	if _, err := client.DoContext(ctx, request); !errors.Is(err, context.Canceled) || time.Since(start) > 500*time.Millisecond {
+		t.Log("Unexpected error", err, time.Since(start))
+		t.FailNow()
+	}
+
+	// The client timeout still applies
+	client.Timeout = 20 * time.Millisecond
+	if _, err := client.Do(request); !errors.Is(err, context.DeadlineExceeded) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
+
+func TestLockContext(t *testing.T) {
+	Lock.Lock("test")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := LockContext(ctx, "test"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	// The abandoned lock attempt releases the lock again
+	Lock.Unlock("test")
+	if err := LockContext(context.Background(), "test"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	Lock.Unlock("test")
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/emcy"
 	"log"
 	"os"
 	"os/signal"
@@ -31,7 +32,7 @@ func main() {
 	log.Println("+------+--------------+-------------------------+")
 	bus.SubscribeFunc(logCANFrame)
 
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	signal.Notify(c, os.Kill)
 
@@ -57,7 +58,15 @@ func logCANFrame(frm can.Frame) {
 	case canopen.MessageTypeHeartbeat:
 		msgType = "Heartbeat"
 	case canopen.MessageTypeSync:
-		msgType = "Sync"
+		// SYNC and EMCY share the message type, EMCY messages have a node id
+		if canopenFrm.IsSync() {
+			msgType = "Sync"
+		} else {
+			msgType = "Emergency"
+			if emergency, err := emcy.Decode(canopen.NewFrame(canopenFrm.CobID, frm.Data[:frm.Length])); err == nil {
+				data = emergency.ErrorCode.String()
+			}
+		}
 	case canopen.MessageTypeTimestamp:
 		msgType = "Timestamp"
 		if time, _ := canopenFrm.Timestamp(); time != nil {
@@ -68,7 +77,7 @@ func logCANFrame(frm can.Frame) {
 	case canopen.MessageTypeRSDO:
 		msgType = "SDO Request"
 	default:
-		msgType = "Unkown"
+		msgType = "Unknown"
 	}
 
 	log.Printf("| %-4d | %-12s | % -23X | %s", canopenFrm.NodeID(), msgType, frm.Data, data)
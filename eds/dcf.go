@@ -0,0 +1,176 @@
+package eds
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteDCF writes the file as device configuration file (DCF).
+// The current values of the object dictionary are written as ParameterValue, the [DeviceComissioning]
+// section contains the NodeID and Baudrate of the file.
+func (file *File) WriteDCF(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+
+	comissioning := &Section{Name: sectionDeviceComissioning}
+	for _, section := range file.Sections {
+		if !strings.EqualFold(section.Name, sectionDeviceComissioning) {
+			writeSection(buf, section)
+			continue
+		}
+
+		// Keep all other entries of an existing section
+		for _, entry := range section.Entries {
+			comissioning.Set(entry.Key, entry.Value)
+		}
+	}
+	comissioning.Set("NodeID", strconv.Itoa(int(file.NodeID)))
+	comissioning.Set("Baudrate", strconv.Itoa(int(file.Baudrate)))
+	writeSection(buf, comissioning)
+
+	var mandatory, optional, manufacturer []uint16
+	for _, index := range file.ObjectDictionary.Indexes() {
+		switch {
+		case index == 0x1000 || index == 0x1001 || index == 0x1018:
+			mandatory = append(mandatory, index)
+		case index >= 0x2000 && index < 0x6000:
+			manufacturer = append(manufacturer, index)
+		default:
+			optional = append(optional, index)
+		}
+	}
+
+	for _, objects := range []struct {
+		name    string
+		indexes []uint16
+	}{
+		{sectionMandatoryObjects, mandatory},
+		{sectionOptionalObjects, optional},
+		{sectionManufacturerObjects, manufacturer},
+	} {
+		writeSection(buf, objectListSection(objects.name, objects.indexes))
+
+		for _, index := range objects.indexes {
+			if err := file.writeObject(buf, file.ObjectDictionary.Object(index)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return buf.Flush()
+}
+
+func (file *File) writeObject(buf *bufio.Writer, object *od.Object) error {
+	section := &Section{Name: fmt.Sprintf("%04X", object.Index)}
+	section.Set("ParameterName", object.Name)
+	section.Set("ObjectType", fmt.Sprintf("0x%X", byte(object.ObjectType)))
+
+	// Objects with a single value
+	if object.ObjectType != od.OBJECT_TYPE_ARRAY && object.ObjectType != od.OBJECT_TYPE_RECORD {
+		if variable := object.SubIndex(0); variable != nil {
+			if err := file.setVariable(section, object.Index, variable); err != nil {
+				return err
+			}
+		}
+
+		writeSection(buf, section)
+		return nil
+	}
+
+	variables := object.SubIndexes()
+	section.Set("SubNumber", fmt.Sprintf("0x%X", len(variables)))
+	writeSection(buf, section)
+
+	for _, variable := range variables {
+		subSection := &Section{Name: fmt.Sprintf("%04Xsub%X", object.Index, variable.SubIndex)}
+		subSection.Set("ParameterName", variable.Name)
+		subSection.Set("ObjectType", fmt.Sprintf("0x%X", byte(od.OBJECT_TYPE_VAR)))
+		if err := file.setVariable(subSection, object.Index, variable); err != nil {
+			return err
+		}
+
+		writeSection(buf, subSection)
+	}
+
+	return nil
+}
+
+func (file *File) setVariable(section *Section, index uint16, variable *od.Variable) error {
+	section.Set("DataType", fmt.Sprintf("0x%04X", byte(variable.DataType)))
+	section.Set("AccessType", FormatAccessType(variable.AccessType))
+
+	defaultValue := formatValue(variable.DataType, variable.DefaultValue)
+	if variable.Relative && !sdo.IsReversed(variable.DataType) && sdo.ParseUInt(append([]byte{}, variable.DefaultValue...)) >= uint64(file.NodeID) {
+		defaultValue = file.formatRelativeValue(variable.DefaultValue)
+	}
+	section.Set("DefaultValue", defaultValue)
+
+	if len(variable.LowLimit) > 0 {
+		section.Set("LowLimit", formatValue(variable.DataType, variable.LowLimit))
+	}
+
+	if len(variable.HighLimit) > 0 {
+		section.Set("HighLimit", formatValue(variable.DataType, variable.HighLimit))
+	}
+
+	pdoMapping := "0"
+	if variable.PDOMapping {
+		pdoMapping = "1"
+	}
+	section.Set("PDOMapping", pdoMapping)
+
+	value, abortCode := file.ObjectDictionary.Value(canopen.NewObjectIndex(index, variable.SubIndex))
+	if abortCode != canopen.NO_ERROR {
+		return fmt.Errorf("object %04X sub %d: %s", index, variable.SubIndex, canopen.GetAbortCodeText(abortCode))
+	}
+	section.Set("ParameterValue", formatValue(variable.DataType, value))
+
+	return nil
+}
+
+// formatRelativeValue writes a value which includes the node ID as $NODEID+offset
+func (file *File) formatRelativeValue(data []byte) string {
+	offset := sdo.ParseUInt(append([]byte{}, data...)) - uint64(file.NodeID)
+	return fmt.Sprintf("%s+0x%X", nodeIDVariable, offset)
+}
+
+// FormatAccessType returns the EDS representation of an access type.
+func FormatAccessType(accessType od.AccessType) string {
+	for s, t := range accessTypes {
+		if t == accessType {
+			return s
+		}
+	}
+
+	return "rw"
+}
+
+// formatValue returns the EDS representation of the value of a data type
+func formatValue(dataType sdo.SDODataType, data []byte) string {
+	// ByteToDataType reverses the bytes of signed integers in place
+	value, _ := sdo.ByteToDataType(dataType, append([]byte{}, data...))
+	return value
+}
+
+func objectListSection(name string, indexes []uint16) *Section {
+	section := &Section{Name: name}
+	section.Set("SupportedObjects", strconv.Itoa(len(indexes)))
+	for i, index := range indexes {
+		section.Set(strconv.Itoa(i+1), fmt.Sprintf("0x%04X", index))
+	}
+
+	return section
+}
+
+func writeSection(buf *bufio.Writer, section *Section) {
+	fmt.Fprintf(buf, "[%s]\n", section.Name)
+	for _, entry := range section.Entries {
+		fmt.Fprintf(buf, "%s=%s\n", entry.Key, strings.ReplaceAll(entry.Value, "\n", " "))
+	}
+	fmt.Fprintln(buf)
+}
@@ -0,0 +1,340 @@
+package eds
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File represents an electronic data sheet (EDS) or device configuration file (DCF) as specified in CiA 306.
+type File struct {
+	// NodeID is used for values which are relative to the node ID ($NODEID)
+	NodeID uint8
+	// Baudrate is the bit rate of the device in kbit/s (DCF only)
+	Baudrate uint16
+
+	// Sections contains all sections which do not describe objects (FileInfo, DeviceInfo, Comments, ...)
+	Sections []*Section
+
+	ObjectDictionary *od.ObjectDictionary
+}
+
+// Names of the sections which are not kept in File.Sections, because they are derived from the object dictionary
+const (
+	sectionDeviceComissioning  = "DeviceComissioning"
+	sectionMandatoryObjects    = "MandatoryObjects"
+	sectionOptionalObjects     = "OptionalObjects"
+	sectionManufacturerObjects = "ManufacturerObjects"
+)
+
+var (
+	objectSection    = regexp.MustCompile(`^([0-9A-Fa-f]{4})$`)
+	subObjectSection = regexp.MustCompile(`^([0-9A-Fa-f]{4})[sS][uU][bB]([0-9A-Fa-f]{1,2})$`)
+	nameSection      = regexp.MustCompile(`^([0-9A-Fa-f]{4})[nN][aA][mM][eE]$`)
+	valueSection     = regexp.MustCompile(`^([0-9A-Fa-f]{4})[vV][aA][lL][uU][eE]$`)
+)
+
+// Section returns the section with the name or nil if it does not exist.
+func (file *File) Section(name string) *Section {
+	for _, section := range file.Sections {
+		if strings.EqualFold(section.Name, name) {
+			return section
+		}
+	}
+
+	return nil
+}
+
+// Parse reads an EDS or DCF file.
+// Values relative to the node ID are resolved with nodeID, or with the NodeID of a DCF if nodeID is 0.
+func Parse(r io.Reader, nodeID uint8) (*File, error) {
+	sections, err := parseSections(r)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{
+		NodeID:           nodeID,
+		ObjectDictionary: od.NewObjectDictionary(),
+	}
+
+	objects := map[uint16]*Section{}
+	subObjects := map[uint16][]*Section{}
+	names := map[uint16]*Section{}
+	values := map[uint16]*Section{}
+	var indexes []uint16
+
+	for _, section := range sections {
+		if match := objectSection.FindStringSubmatch(section.Name); match != nil {
+			index := parseIndex(match[1])
+			objects[index] = section
+			indexes = append(indexes, index)
+
+		} else if match := subObjectSection.FindStringSubmatch(section.Name); match != nil {
+			index := parseIndex(match[1])
+			subObjects[index] = append(subObjects[index], section)
+
+		} else if match := nameSection.FindStringSubmatch(section.Name); match != nil {
+			names[parseIndex(match[1])] = section
+
+		} else if match := valueSection.FindStringSubmatch(section.Name); match != nil {
+			values[parseIndex(match[1])] = section
+
+		} else if strings.EqualFold(section.Name, sectionDeviceComissioning) {
+			if err := file.parseDeviceComissioning(section); err != nil {
+				return nil, err
+			}
+			file.Sections = append(file.Sections, section)
+
+		} else if !strings.EqualFold(section.Name, sectionMandatoryObjects) &&
+			!strings.EqualFold(section.Name, sectionOptionalObjects) &&
+			!strings.EqualFold(section.Name, sectionManufacturerObjects) {
+			file.Sections = append(file.Sections, section)
+		}
+	}
+
+	for index, sections := range subObjects {
+		if _, ok := objects[index]; !ok {
+			return nil, ParseError{Line: sections[0].Line, Message: fmt.Sprintf("sub object of undefined object %04X", index)}
+		}
+	}
+
+	for _, index := range indexes {
+		object, err := file.parseObject(index, objects[index], subObjects[index], names[index], values[index])
+		if err != nil {
+			return nil, err
+		}
+
+		file.ObjectDictionary.Add(object)
+
+		// The actual values of a DCF are set after the object is initialized with the default values
+		if object.ObjectType != od.OBJECT_TYPE_ARRAY && object.ObjectType != od.OBJECT_TYPE_RECORD {
+			err = file.parseParameterValue(index, object.SubIndex(0), objects[index])
+		}
+
+		for _, subSection := range subObjects[index] {
+			if err != nil {
+				break
+			}
+
+			match := subObjectSection.FindStringSubmatch(subSection.Name)
+			if variable := object.SubIndex(parseSubIndex(match[2])); variable != nil {
+				err = file.parseParameterValue(index, variable, subSection)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+func (file *File) parseDeviceComissioning(section *Section) error {
+	if value, ok := section.Get("NodeID"); ok && len(value) > 0 {
+		nodeID, err := strconv.ParseUint(value, 0, 8)
+		if err != nil || nodeID > uint64(canopen.MaxNodeID) {
+			return ParseError{Line: section.line("NodeID"), Message: fmt.Sprintf("invalid node id %q", value)}
+		}
+
+		if file.NodeID == 0 {
+			file.NodeID = uint8(nodeID)
+		}
+	}
+
+	if value, ok := section.Get("Baudrate"); ok && len(value) > 0 {
+		baudrate, err := strconv.ParseUint(value, 0, 16)
+		if err != nil {
+			return ParseError{Line: section.line("Baudrate"), Message: fmt.Sprintf("invalid baudrate %q", value)}
+		}
+		file.Baudrate = uint16(baudrate)
+	}
+
+	return nil
+}
+
+func (file *File) parseObject(index uint16, section *Section, subSections []*Section, names *Section, values *Section) (*od.Object, error) {
+	name, _ := section.Get("ParameterName")
+
+	objectType := od.OBJECT_TYPE_VAR
+	if value, ok := section.Get("ObjectType"); ok && len(value) > 0 {
+		n, err := strconv.ParseUint(value, 0, 8)
+		if err != nil {
+			return nil, ParseError{Line: section.line("ObjectType"), Message: fmt.Sprintf("invalid object type %q", value)}
+		}
+		objectType = od.ObjectType(n)
+	}
+
+	object := od.NewObject(index, name, objectType)
+
+	// Objects with a single value
+	if objectType != od.OBJECT_TYPE_ARRAY && objectType != od.OBJECT_TYPE_RECORD {
+		variable, err := file.parseVariable(section, 0, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return object.AddSubIndex(variable), nil
+	}
+
+	// Compact storage of sub objects with the same properties
+	if value, ok := section.Get("CompactSubObj"); ok && len(value) > 0 {
+		n, err := strconv.ParseUint(value, 0, 8)
+		if err != nil {
+			return nil, ParseError{Line: section.line("CompactSubObj"), Message: fmt.Sprintf("invalid number of sub objects %q", value)}
+		}
+
+		if n > 0 {
+			return file.parseCompactObject(object, section, uint8(n), names, values)
+		}
+	}
+
+	for _, subSection := range subSections {
+		match := subObjectSection.FindStringSubmatch(subSection.Name)
+		subIndex := parseSubIndex(match[2])
+		subName, _ := subSection.Get("ParameterName")
+
+		variable, err := file.parseVariable(subSection, subIndex, subName)
+		if err != nil {
+			return nil, err
+		}
+
+		if object.SubIndex(subIndex) != nil {
+			return nil, ParseError{Line: subSection.Line, Message: fmt.Sprintf("duplicate sub index %d of object %04X", subIndex, index)}
+		}
+		object.AddSubIndex(variable)
+	}
+
+	return object, nil
+}
+
+// parseCompactObject creates the sub indexes 0 to n of an object with CompactSubObj.
+// The names and default values of the sub indexes can be defined in the [xxxxName] and [xxxxValue] sections.
+func (file *File) parseCompactObject(object *od.Object, section *Section, n uint8, names *Section, values *Section) (*od.Object, error) {
+	object.AddSubIndex(&od.Variable{
+		Name:         "NrOfObjects",
+		SubIndex:     0,
+		DataType:     sdo.DATA_TYPE_UNSIGNED_8,
+		AccessType:   od.ACCESS_TYPE_RO,
+		DefaultValue: []byte{n},
+	})
+
+	for i := uint8(1); i <= n && i != 0; i++ {
+		name := fmt.Sprintf("%s%d", object.Name, i)
+		if names != nil {
+			if value, ok := names.Get(strconv.Itoa(int(i))); ok {
+				name = value
+			}
+		}
+
+		variable, err := file.parseVariable(section, i, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if values != nil {
+			if value, ok := values.Get(strconv.Itoa(int(i))); ok {
+				variable.DefaultValue, variable.Relative, err = ParseValue(variable.DataType, value, file.NodeID)
+				if err != nil {
+					return nil, ParseError{Line: values.line(strconv.Itoa(int(i))), Message: err.Error()}
+				}
+			}
+		}
+
+		object.AddSubIndex(variable)
+	}
+
+	return object, nil
+}
+
+func (file *File) parseVariable(section *Section, subIndex uint8, name string) (*od.Variable, error) {
+	variable := &od.Variable{
+		Name:       name,
+		SubIndex:   subIndex,
+		AccessType: od.ACCESS_TYPE_RW,
+	}
+
+	if value, ok := section.Get("DataType"); ok {
+		n, err := strconv.ParseUint(value, 0, 16)
+		if err != nil || n > 0xFF {
+			return nil, ParseError{Line: section.line("DataType"), Message: fmt.Sprintf("invalid data type %q", value)}
+		}
+		variable.DataType = sdo.SDODataType(n)
+	} else {
+		return nil, ParseError{Line: section.Line, Message: fmt.Sprintf("missing DataType in [%s]", section.Name)}
+	}
+
+	if value, ok := section.Get("AccessType"); ok {
+		accessType, err := ParseAccessType(value)
+		if err != nil {
+			return nil, ParseError{Line: section.line("AccessType"), Message: err.Error()}
+		}
+		variable.AccessType = accessType
+	}
+
+	if value, ok := section.Get("PDOMapping"); ok && len(value) > 0 {
+		pdoMapping, err := strconv.ParseUint(value, 0, 1)
+		if err != nil {
+			return nil, ParseError{Line: section.line("PDOMapping"), Message: fmt.Sprintf("invalid PDO mapping %q", value)}
+		}
+		variable.PDOMapping = pdoMapping == 1
+	}
+
+	var err error
+	for _, limit := range []struct {
+		key   string
+		value *[]byte
+	}{
+		{"LowLimit", &variable.LowLimit},
+		{"HighLimit", &variable.HighLimit},
+	} {
+		if value, ok := section.Get(limit.key); ok {
+			if *limit.value, _, err = ParseValue(variable.DataType, value, file.NodeID); err != nil {
+				return nil, ParseError{Line: section.line(limit.key), Message: err.Error()}
+			}
+		}
+	}
+
+	if value, ok := section.Get("DefaultValue"); ok {
+		if variable.DefaultValue, variable.Relative, err = ParseValue(variable.DataType, value, file.NodeID); err != nil {
+			return nil, ParseError{Line: section.line("DefaultValue"), Message: err.Error()}
+		}
+	}
+
+	return variable, nil
+}
+
+// parseParameterValue sets the actual value of a variable in a device configuration file.
+func (file *File) parseParameterValue(index uint16, variable *od.Variable, section *Section) error {
+	value, ok := section.Get("ParameterValue")
+	if !ok {
+		return nil
+	}
+
+	data, _, err := ParseValue(variable.DataType, value, file.NodeID)
+	if err != nil {
+		return ParseError{Line: section.line("ParameterValue"), Message: err.Error()}
+	}
+
+	if abortCode := file.ObjectDictionary.SetValue(canopen.NewObjectIndex(index, variable.SubIndex), data); abortCode != canopen.NO_ERROR {
+		return ParseError{Line: section.line("ParameterValue"), Message: canopen.GetAbortCodeText(abortCode)}
+	}
+
+	return nil
+}
+
+func parseIndex(s string) uint16 {
+	index, _ := strconv.ParseUint(s, 16, 16)
+	return uint16(index)
+}
+
+func parseSubIndex(s string) uint8 {
+	subIndex, _ := strconv.ParseUint(s, 16, 8)
+	return uint8(subIndex)
+}
@@ -0,0 +1,270 @@
+package eds
+
+import (
+	"bytes"
+	"errors"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"strings"
+	"testing"
+)
+
+const testEDS = `[FileInfo]
+FileName=test.eds
+FileVersion=1
+; Comments are ignored
+[DeviceInfo]
+VendorName=Test
+ProductName=Test Device
+
+[MandatoryObjects]
+SupportedObjects=2
+1=0x1000
+2=0x1018
+
+[1000]
+ParameterName=Device type
+ObjectType=0x7
+DataType=0x0007
+AccessType=ro
+DefaultValue=0x00010191
+PDOMapping=0
+
+[1018]
+ParameterName=Identity Object
+ObjectType=0x9
+SubNumber=2
+
+[1018sub0]
+ParameterName=Number of entries
+ObjectType=0x7
+DataType=0x0005
+AccessType=ro
+DefaultValue=1
+
+[1018sub1]
+ParameterName=Vendor-ID
+ObjectType=0x7
+DataType=0x0007
+AccessType=ro
+DefaultValue=0x12345678
+
+[1800sub1]
+ParameterName=COB-ID used by TPDO
+ObjectType=0x7
+DataType=0x0007
+AccessType=rw
+DefaultValue=$NODEID+0x180
+
+[1800]
+ParameterName=TPDO communication parameter
+ObjectType=0x9
+SubNumber=1
+
+[2000]
+ParameterName=Temperatures
+ObjectType=0x8
+DataType=0x0003
+AccessType=rww
+PDOMapping=1
+LowLimit=-40
+HighLimit=120
+DefaultValue=20
+CompactSubObj=3
+
+[2000Name]
+NrOfEntries=1
+2=Outside
+
+[2000Value]
+NrOfEntries=1
+3=-0x10
+`
+
+func TestParse(t *testing.T) {
+	file, err := Parse(strings.NewReader(testEDS), 5)
+	if err != nil {
+		t.Log("Parse error", err)
+		t.FailNow()
+	}
+
+	if section := file.Section("DeviceInfo"); section == nil {
+		t.Log("Missing section DeviceInfo")
+		t.FailNow()
+	} else if value, _ := section.Get("productname"); value != "Test Device" {
+		t.Log("Unexpected product name", value)
+		t.FailNow()
+	}
+
+	for _, test := range []struct {
+		objectIndex canopen.ObjectIndex
+		expected    []byte
+	}{
+		{canopen.NewObjectIndex(0x1000, 0), []byte{0x91, 0x01, 0x01, 0x00}},
+		{canopen.NewObjectIndex(0x1018, 0), []byte{0x01}},
+		{canopen.NewObjectIndex(0x1018, 1), []byte{0x78, 0x56, 0x34, 0x12}},
+		{canopen.NewObjectIndex(0x1800, 1), []byte{0x85, 0x01, 0x00, 0x00}},
+		{canopen.NewObjectIndex(0x2000, 0), []byte{0x03}},
+		{canopen.NewObjectIndex(0x2000, 1), []byte{0x14, 0x00}},
+		{canopen.NewObjectIndex(0x2000, 3), []byte{0xF0, 0xFF}},
+	} {
+		data, abortCode := file.ObjectDictionary.Value(test.objectIndex)
+		if abortCode != canopen.NO_ERROR || !bytes.Equal(data, test.expected) {
+			t.Log("Unexpected value", test.objectIndex.String(), data, abortCode)
+			t.FailNow()
+		}
+	}
+
+	variable, _ := file.ObjectDictionary.Variable(canopen.NewObjectIndex(0x2000, 2))
+	if variable.Name != "Outside" || variable.AccessType != od.ACCESS_TYPE_RWW || !variable.PDOMapping {
+		t.Log("Unexpected compact sub object", variable)
+		t.FailNow()
+	}
+
+	if abortCode := file.ObjectDictionary.Write(canopen.NewObjectIndex(0x2000, 2), []byte{0x79, 0x00}); abortCode != canopen.SDO_ERR_VALUE_HIGH {
+		t.Log("High limit not applied", abortCode)
+		t.FailNow()
+	}
+
+	variable, _ = file.ObjectDictionary.Variable(canopen.NewObjectIndex(0x1800, 1))
+	if !variable.Relative {
+		t.Log("Value is not relative to node id")
+		t.FailNow()
+	}
+}
+
+func TestParseTwosComplement(t *testing.T) {
+	// Hexadecimal values of signed data types are the two's complement
+	file, err := Parse(strings.NewReader(`[2001]
+ParameterName=Offset
+ObjectType=0x7
+DataType=0x0003
+AccessType=rw
+LowLimit=0x8000
+HighLimit=0x7FFF
+DefaultValue=0xFFFE
+
+[2002]
+ParameterName=Position
+ObjectType=0x7
+DataType=0x0004
+AccessType=rw
+LowLimit=0x80000000
+DefaultValue=0x80000000
+`), 1)
+	if err != nil {
+		t.Log("Parse error", err)
+		t.FailNow()
+	}
+
+	if data, _ := file.ObjectDictionary.Value(canopen.NewObjectIndex(0x2001, 0)); !bytes.Equal(data, []byte{0xFE, 0xFF}) {
+		t.Log("Unexpected value", data)
+		t.FailNow()
+	}
+
+	variable, _ := file.ObjectDictionary.Variable(canopen.NewObjectIndex(0x2001, 0))
+	if !bytes.Equal(variable.LowLimit, []byte{0x00, 0x80}) || !bytes.Equal(variable.HighLimit, []byte{0xFF, 0x7F}) {
+		t.Log("Unexpected limits", variable.LowLimit, variable.HighLimit)
+		t.FailNow()
+	}
+
+	// -32768 is the lowest allowed value
+	if abortCode := file.ObjectDictionary.Write(canopen.NewObjectIndex(0x2001, 0), []byte{0x00, 0x80}); abortCode != canopen.NO_ERROR {
+		t.Log("Low limit not applied", abortCode)
+		t.FailNow()
+	}
+
+	if data, _ := file.ObjectDictionary.Value(canopen.NewObjectIndex(0x2002, 0)); !bytes.Equal(data, []byte{0x00, 0x00, 0x00, 0x80}) {
+		t.Log("Unexpected value", data)
+		t.FailNow()
+	}
+}
+
+func TestParseError(t *testing.T) {
+	for _, test := range []struct {
+		eds  string
+		line int
+	}{
+		{"[1000]\nParameterName=Device type\nDataType=0x0007\nDefaultValue=0x100000000\n", 4},
+		{"[2001]\nParameterName=Offset\nDataType=0x0003\nDefaultValue=0x10000\n", 4},
+		{"[1000]\nParameterName=Device type\nAccessType=ro\n", 1},
+		{"[1000]\nDataType=0x0007\nAccessType=rx\n", 3},
+		{"[FileInfo]\nFileName\n", 2},
+		{"FileName=test.eds\n", 1},
+		{"[1000sub1]\nDataType=0x0007\n", 1},
+	} {
+		_, err := Parse(strings.NewReader(test.eds), 1)
+
+		var parseError ParseError
+		if !errors.As(err, &parseError) || parseError.Line != test.line {
+			t.Log("Unexpected error", test.eds, err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestWriteDCF(t *testing.T) {
+	file, err := Parse(strings.NewReader(testEDS), 5)
+	if err != nil {
+		t.Log("Parse error", err)
+		t.FailNow()
+	}
+
+	file.Baudrate = 250
+	file.ObjectDictionary.Write(canopen.NewObjectIndex(0x2000, 2), []byte{0x2A, 0x00})
+
+	var buf bytes.Buffer
+	if err := file.WriteDCF(&buf); err != nil {
+		t.Log("Write error", err)
+		t.FailNow()
+	}
+
+	dcf, err := Parse(&buf, 0)
+	if err != nil {
+		t.Log("Parse error", err, buf.String())
+		t.FailNow()
+	}
+
+	if dcf.NodeID != 5 || dcf.Baudrate != 250 {
+		t.Log("Unexpected device comissioning", dcf.NodeID, dcf.Baudrate)
+		t.FailNow()
+	}
+
+	if value, _ := dcf.Section("FileInfo").Get("FileName"); value != "test.eds" {
+		t.Log("Unexpected file name", value)
+		t.FailNow()
+	}
+
+	indexes := file.ObjectDictionary.Indexes()
+	if len(dcf.ObjectDictionary.Indexes()) != len(indexes) {
+		t.Log("Unexpected objects", dcf.ObjectDictionary.Indexes())
+		t.FailNow()
+	}
+
+	for _, index := range indexes {
+		expected := file.ObjectDictionary.Object(index)
+		actual := dcf.ObjectDictionary.Object(index)
+		if actual == nil || actual.Name != expected.Name || actual.ObjectType != expected.ObjectType || len(actual.SubIndexes()) != len(expected.SubIndexes()) {
+			t.Log("Object does not match", index, expected, actual)
+			t.FailNow()
+		}
+
+		for _, variable := range expected.SubIndexes() {
+			other := actual.SubIndex(variable.SubIndex)
+			if other == nil || other.Name != variable.Name || other.DataType != variable.DataType || other.AccessType != variable.AccessType ||
+				other.PDOMapping != variable.PDOMapping || other.Relative != variable.Relative ||
+				!bytes.Equal(other.DefaultValue, variable.DefaultValue) || !bytes.Equal(other.LowLimit, variable.LowLimit) || !bytes.Equal(other.HighLimit, variable.HighLimit) {
+				t.Log("Variable does not match", index, variable, other)
+				t.FailNow()
+			}
+
+			objectIndex := canopen.NewObjectIndex(index, variable.SubIndex)
+			expectedValue, _ := file.ObjectDictionary.Value(objectIndex)
+			actualValue, _ := dcf.ObjectDictionary.Value(objectIndex)
+			if !bytes.Equal(actualValue, expectedValue) {
+				t.Log("Value does not match", objectIndex.String(), expectedValue, actualValue)
+				t.FailNow()
+			}
+		}
+	}
+}
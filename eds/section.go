@@ -0,0 +1,124 @@
+package eds
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError describes a problem at a specific line of an EDS or DCF file.
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Entry is a key value pair of a section.
+type Entry struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// Section is a section of an INI file, the entries are kept in the order of the file.
+type Section struct {
+	Name    string
+	Line    int
+	Entries []*Entry
+}
+
+// Get returns the value of a key, keys are not case-sensitive.
+func (section *Section) Get(key string) (string, bool) {
+	if entry := section.entry(key); entry != nil {
+		return entry.Value, true
+	}
+
+	return "", false
+}
+
+// Set adds or replaces the value of a key.
+func (section *Section) Set(key string, value string) {
+	if entry := section.entry(key); entry != nil {
+		entry.Value = value
+		return
+	}
+
+	section.Entries = append(section.Entries, &Entry{Key: key, Value: value})
+}
+
+// line returns the line of the key or the line of the section if the key does not exist
+func (section *Section) line(key string) int {
+	if entry := section.entry(key); entry != nil {
+		return entry.Line
+	}
+
+	return section.Line
+}
+
+func (section *Section) entry(key string) *Entry {
+	for _, entry := range section.Entries {
+		if strings.EqualFold(entry.Key, key) {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// parseSections reads all sections of an INI file as used by CiA 306.
+func parseSections(r io.Reader) ([]*Section, error) {
+	var sections []*Section
+	var section *Section
+	names := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			// Skip the UTF-8 byte order mark
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+
+		// Skip empty lines and comments
+		if len(text) == 0 || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, ParseError{Line: line, Message: fmt.Sprintf("invalid section header %q", text)}
+			}
+
+			name := strings.TrimSpace(text[1 : len(text)-1])
+			if names[strings.ToLower(name)] {
+				return nil, ParseError{Line: line, Message: fmt.Sprintf("duplicate section [%s]", name)}
+			}
+			names[strings.ToLower(name)] = true
+
+			section = &Section{Name: name, Line: line}
+			sections = append(sections, section)
+			continue
+		}
+
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, ParseError{Line: line, Message: fmt.Sprintf("expected key=value, got %q", text)}
+		}
+
+		if section == nil {
+			return nil, ParseError{Line: line, Message: "key outside of a section"}
+		}
+
+		section.Entries = append(section.Entries, &Entry{
+			Key:   strings.TrimSpace(key),
+			Value: strings.TrimSpace(value),
+			Line:  line,
+		})
+	}
+
+	return sections, scanner.Err()
+}
@@ -0,0 +1,135 @@
+package eds
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"strconv"
+	"strings"
+)
+
+// nodeIDVariable is replaced by the node ID in values
+const nodeIDVariable = "$NODEID"
+
+var accessTypes = map[string]od.AccessType{
+	"rw":    od.ACCESS_TYPE_RW,
+	"ro":    od.ACCESS_TYPE_RO,
+	"wo":    od.ACCESS_TYPE_WO,
+	"rwr":   od.ACCESS_TYPE_RWR,
+	"rww":   od.ACCESS_TYPE_RWW,
+	"const": od.ACCESS_TYPE_CONST,
+}
+
+// ParseAccessType returns the access type of its EDS representation (ro, wo, rw, rwr, rww, const).
+func ParseAccessType(s string) (od.AccessType, error) {
+	if accessType, ok := accessTypes[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return accessType, nil
+	}
+
+	return od.ACCESS_TYPE_RW, fmt.Errorf("invalid access type %q", s)
+}
+
+// ParseValue converts a value of an EDS to the bytes of the data type.
+// Integers can be decimal, hexadecimal (0x) or octal (0) and may contain $NODEID, e.g. $NODEID+0x180.
+// The returned bool indicates that the value is relative to the node ID.
+func ParseValue(dataType sdo.SDODataType, s string, nodeID uint8) ([]byte, bool, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		return nil, false, nil
+	}
+
+	relative := false
+	if isInteger(dataType) {
+		var err error
+		if s, relative, err = normalizeInteger(dataType, s, nodeID); err != nil {
+			return nil, relative, err
+		}
+	}
+
+	data, failed := sdo.DataTypeToByte(dataType, s)
+	if failed {
+		return nil, relative, fmt.Errorf("invalid value %q for data type 0x%04X", s, byte(dataType))
+	}
+
+	return data, relative, nil
+}
+
+// normalizeInteger resolves $NODEID and converts the integer to the decimal representation expected by sdo.DataTypeToByte
+func normalizeInteger(dataType sdo.SDODataType, s string, nodeID uint8) (string, bool, error) {
+	relative := false
+	isSigned := sdo.IsReversed(dataType)
+
+	var signedSum int64
+	var unsignedSum uint64
+	for _, term := range strings.Split(s, "+") {
+		term = strings.TrimSpace(term)
+		if strings.EqualFold(term, nodeIDVariable) {
+			relative = true
+			signedSum += int64(nodeID)
+			unsignedSum += uint64(nodeID)
+			continue
+		}
+
+		if isSigned {
+			n, err := parseSigned(term, sdo.DataTypeSize(dataType))
+			if err != nil {
+				return s, relative, fmt.Errorf("invalid integer %q", s)
+			}
+			signedSum += n
+		} else {
+			n, err := strconv.ParseUint(term, 0, 64)
+			if err != nil {
+				return s, relative, fmt.Errorf("invalid unsigned integer %q", s)
+			}
+			unsignedSum += n
+		}
+	}
+
+	size := sdo.DataTypeSize(dataType)
+	if isSigned {
+		if size < 8 && (signedSum < -(1<<(8*size-1)) || signedSum >= 1<<(8*size-1)) {
+			return s, relative, fmt.Errorf("value %q exceeds data type 0x%04X", s, byte(dataType))
+		}
+
+		return strconv.FormatInt(signedSum, 10), relative, nil
+	}
+
+	// Booleans are parsed with strconv.ParseBool
+	if dataType == sdo.DATA_TYPE_BOOLEAN {
+		if unsignedSum > 1 {
+			return s, relative, fmt.Errorf("invalid boolean %q", s)
+		}
+	}
+
+	if size < 8 && unsignedSum >= 1<<(8*size) {
+		return s, relative, fmt.Errorf("value %q exceeds data type 0x%04X", s, byte(dataType))
+	}
+
+	return strconv.FormatUint(unsignedSum, 10), relative, nil
+}
+
+// parseSigned parses a term of a signed integer with size bytes.
+// Hexadecimal terms are the two's complement of the value, e.g. 0x8000 is -32768 for INTEGER_16.
+func parseSigned(term string, size int) (int64, error) {
+	if !strings.HasPrefix(term, "0x") && !strings.HasPrefix(term, "0X") {
+		return strconv.ParseInt(term, 0, 64)
+	}
+
+	n, err := strconv.ParseUint(term, 0, 8*size)
+	if err != nil {
+		return 0, err
+	}
+
+	// Sign extend the value to 64 bits
+	shift := 64 - 8*size
+	return int64(n<<shift) >> shift, nil
+}
+
+func isInteger(dataType sdo.SDODataType) bool {
+	switch dataType {
+	case sdo.DATA_TYPE_REAL_32, sdo.DATA_TYPE_REAL_64:
+		return false
+	}
+
+	return sdo.DataTypeSize(dataType) > 0 && dataType != sdo.DATA_TYPE_TIME_OF_DAY && dataType != sdo.DATA_TYPE_TIME_DIFFERENCE
+}
@@ -0,0 +1,151 @@
+package emcy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// Emergency is a decoded emergency message.
+type Emergency struct {
+	NodeID        uint8
+	ErrorCode     ErrorCode
+	ErrorRegister ErrorRegister
+	// ManufacturerData contains the manufacturer specific error information
+	ManufacturerData [5]byte
+	Time             time.Time
+}
+
+// Decode returns the emergency of an EMCY frame.
+func Decode(frame canopen.Frame) (Emergency, error) {
+	if !frame.IsEmergency() {
+		return Emergency{}, fmt.Errorf("Invalid COB-ID %X", frame.CobID)
+	}
+
+	if n := len(frame.Data); n != 8 {
+		return Emergency{}, fmt.Errorf("Invalid data length %d", n)
+	}
+
+	emergency := Emergency{
+		NodeID:        frame.NodeID(),
+		ErrorCode:     ErrorCode(binary.LittleEndian.Uint16(frame.Data[0:2])),
+		ErrorRegister: ErrorRegister(frame.Data[2]),
+		Time:          time.Now(),
+	}
+	copy(emergency.ManufacturerData[:], frame.Data[3:8])
+
+	return emergency, nil
+}
+
+// Frame returns the EMCY frame of the emergency.
+func (emergency Emergency) Frame() canopen.Frame {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint16(data[0:2], uint16(emergency.ErrorCode))
+	data[2] = byte(emergency.ErrorRegister)
+	copy(data[3:8], emergency.ManufacturerData[:])
+
+	return canopen.NewFrame(canopen.MessageTypeEMCY+uint16(emergency.NodeID), data)
+}
+
+// IsReset returns true if the emergency signals that all errors were reset.
+func (emergency Emergency) IsReset() bool {
+	return emergency.ErrorCode == ErrorResetOrNoError
+}
+
+func (emergency Emergency) String() string {
+	return fmt.Sprintf("Node %d: %s (register: %s, manufacturer data: % X)", emergency.NodeID, emergency.ErrorCode, emergency.ErrorRegister, emergency.ManufacturerData)
+}
+
+// EventType describes an emergency event
+type EventType uint8
+
+const (
+	// EventError is emitted when a node reports an error
+	EventError EventType = iota
+	// EventReset is emitted when a node reports that its errors were reset
+	EventReset
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventError:
+		return "Error"
+	case EventReset:
+		return "Error reset"
+	}
+
+	return "Unknown"
+}
+
+// Event is emitted by the consumer for every emergency message.
+type Event struct {
+	Type      EventType
+	Emergency Emergency
+}
+
+// Consumer receives the emergency messages of the nodes on a bus.
+type Consumer struct {
+	bus     *can.Bus
+	handler can.Handler
+	onEvent func(Event)
+
+	lock   sync.Mutex
+	active map[uint8]Emergency
+}
+
+// NewConsumer returns a consumer which listens to the emergency messages of the bus.
+// onEvent is called for every event, it must not block because it is called while the bus dispatches frames.
+func NewConsumer(bus *can.Bus, onEvent func(Event)) *Consumer {
+	consumer := &Consumer{
+		bus:     bus,
+		onEvent: onEvent,
+		active:  map[uint8]Emergency{},
+	}
+
+	consumer.handler = can.NewHandler(consumer.handle)
+	bus.Subscribe(consumer.handler)
+
+	return consumer
+}
+
+// Close stops listening to the emergency messages.
+func (consumer *Consumer) Close() {
+	consumer.bus.Unsubscribe(consumer.handler)
+}
+
+// Active returns the last emergency of a node, if the node has not reset its errors since.
+func (consumer *Consumer) Active(nodeID uint8) (Emergency, bool) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	emergency, ok := consumer.active[nodeID]
+	return emergency, ok
+}
+
+func (consumer *Consumer) handle(frm can.Frame) {
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frm.Length != 8 {
+		return
+	}
+
+	emergency, err := Decode(canopen.CANopenFrame(frm))
+	if err != nil {
+		return
+	}
+
+	event := Event{Type: EventError, Emergency: emergency}
+	consumer.lock.Lock()
+	if emergency.IsReset() {
+		event.Type = EventReset
+		delete(consumer.active, emergency.NodeID)
+	} else {
+		consumer.active[emergency.NodeID] = emergency
+	}
+	consumer.lock.Unlock()
+
+	if consumer.onEvent != nil {
+		consumer.onEvent(event)
+	}
+}
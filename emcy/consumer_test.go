@@ -0,0 +1,77 @@
+package emcy
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"testing"
+	"time"
+)
+
+func TestDescription(t *testing.T) {
+	for code, description := range map[ErrorCode]string{
+		0x0000: "Error reset or no error",
+		0x8130: "Life guard error or heartbeat error",
+		0x3105: "Mains voltage",
+		0x7121: "Motor blocked",
+		0xFF42: "Device specific",
+		0x0042: "Unknown error",
+	} {
+		if code.Description() != description {
+			t.Log("Unexpected description", code, "expected", description)
+			t.FailNow()
+		}
+	}
+
+	if register := RegisterGeneric | RegisterTemperature; register.String() != "Generic, Temperature" {
+		t.Log("Unexpected error register", register)
+		t.FailNow()
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	events := make(chan Event, 10)
+	consumer := NewConsumer(bus, func(event Event) {
+		events <- event
+	})
+	defer consumer.Close()
+
+	expect := func(eventType EventType, code ErrorCode) Emergency {
+		select {
+		case event := <-events:
+			if event.Type != eventType || event.Emergency.NodeID != 5 || event.Emergency.ErrorCode != code {
+				t.Log("Unexpected event", event.Type, event.Emergency)
+				t.FailNow()
+			}
+			return event.Emergency
+		case <-time.After(time.Second):
+			t.Log("Missing event", eventType)
+			t.FailNow()
+		}
+
+		return Emergency{}
+	}
+
+	// SYNC messages are ignored
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeSync, []byte{1}).CANFrame())
+
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeEMCY+5, []byte{0x10, 0x42, 0x09, 1, 2, 3, 4, 5}).CANFrame())
+	emergency := expect(EventError, 0x4210)
+	if emergency.ErrorRegister != RegisterGeneric|RegisterTemperature || emergency.ManufacturerData != [5]byte{1, 2, 3, 4, 5} {
+		t.Log("Unexpected emergency", emergency)
+		t.FailNow()
+	}
+
+	if _, ok := consumer.Active(5); !ok {
+		t.Log("Missing active emergency")
+		t.FailNow()
+	}
+
+	bus.PublishLocal(Emergency{NodeID: 5}.Frame().CANFrame())
+	expect(EventReset, ErrorResetOrNoError)
+
+	if _, ok := consumer.Active(5); ok {
+		t.Log("Unexpected active emergency")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,165 @@
+package emcy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorCode is the 16-bit emergency error code
+type ErrorCode uint16
+
+const (
+	ErrorResetOrNoError     ErrorCode = 0x0000
+	ErrorGeneric            ErrorCode = 0x1000
+	ErrorCurrent            ErrorCode = 0x2000
+	ErrorVoltage            ErrorCode = 0x3000
+	ErrorTemperature        ErrorCode = 0x4000
+	ErrorDeviceHardware     ErrorCode = 0x5000
+	ErrorDeviceSoftware     ErrorCode = 0x6000
+	ErrorAdditionalModules  ErrorCode = 0x7000
+	ErrorMonitoring         ErrorCode = 0x8000
+	ErrorCommunication      ErrorCode = 0x8100
+	ErrorCANOverrun         ErrorCode = 0x8110
+	ErrorCANPassive         ErrorCode = 0x8120
+	ErrorHeartbeat          ErrorCode = 0x8130
+	ErrorBusOffRecovered    ErrorCode = 0x8140
+	ErrorCANIDCollision     ErrorCode = 0x8150
+	ErrorProtocol           ErrorCode = 0x8200
+	ErrorPDOLength          ErrorCode = 0x8210
+	ErrorPDOLengthExceeded  ErrorCode = 0x8220
+	ErrorDAMMPDO            ErrorCode = 0x8230
+	ErrorSyncDataLength     ErrorCode = 0x8240
+	ErrorRPDOTimeout        ErrorCode = 0x8250
+	ErrorExternal           ErrorCode = 0x9000
+	ErrorAdditionalFunction ErrorCode = 0xF000
+	ErrorDeviceSpecific     ErrorCode = 0xFF00
+)
+
+// descriptions contains the error codes of CiA 301 and the drive specific error codes of CiA 402
+var descriptions = map[ErrorCode]string{
+	ErrorResetOrNoError:     "Error reset or no error",
+	ErrorGeneric:            "Generic error",
+	ErrorCurrent:            "Current",
+	0x2100:                  "Current, device input side",
+	0x2200:                  "Current inside the device",
+	0x2300:                  "Current, device output side",
+	0x2310:                  "Continuous over current",
+	0x2311:                  "Continuous over current (device internal)",
+	0x2320:                  "Short circuit/earth leakage",
+	0x2330:                  "Earth leakage",
+	0x2340:                  "Short circuit",
+	ErrorVoltage:            "Voltage",
+	0x3100:                  "Mains voltage",
+	0x3110:                  "Mains over-voltage",
+	0x3120:                  "Mains under-voltage",
+	0x3130:                  "Phase failure",
+	0x3200:                  "Voltage inside the device",
+	0x3210:                  "DC link over-voltage",
+	0x3220:                  "DC link under-voltage",
+	0x3230:                  "Load error",
+	0x3300:                  "Output voltage",
+	ErrorTemperature:        "Temperature",
+	0x4100:                  "Ambient temperature",
+	0x4200:                  "Device temperature",
+	0x4210:                  "Excess temperature device",
+	0x4300:                  "Drive temperature",
+	0x4310:                  "Excess temperature drive",
+	ErrorDeviceHardware:     "Device hardware",
+	ErrorDeviceSoftware:     "Device software",
+	0x6100:                  "Internal software",
+	0x6200:                  "User software",
+	0x6300:                  "Data set",
+	0x6320:                  "Parameter error",
+	ErrorAdditionalModules:  "Additional modules",
+	0x7110:                  "Brake chopper",
+	0x7120:                  "Motor",
+	0x7121:                  "Motor blocked",
+	0x7300:                  "Sensor",
+	0x7305:                  "Incremental sensor 1 fault",
+	0x7310:                  "Speed",
+	0x7320:                  "Position",
+	ErrorMonitoring:         "Monitoring",
+	ErrorCommunication:      "Communication",
+	ErrorCANOverrun:         "CAN overrun (objects lost)",
+	ErrorCANPassive:         "CAN in error passive mode",
+	ErrorHeartbeat:          "Life guard error or heartbeat error",
+	ErrorBusOffRecovered:    "Recovered from bus off",
+	ErrorCANIDCollision:     "CAN-ID collision",
+	ErrorProtocol:           "Protocol error",
+	ErrorPDOLength:          "PDO not processed due to length error",
+	ErrorPDOLengthExceeded:  "PDO length exceeded",
+	ErrorDAMMPDO:            "DAM MPDO not processed, destination object not available",
+	ErrorSyncDataLength:     "Unexpected SYNC data length",
+	ErrorRPDOTimeout:        "RPDO timeout",
+	0x8300:                  "Torque control",
+	0x8311:                  "Excess torque",
+	0x8400:                  "Velocity speed controller",
+	0x8500:                  "Position controller",
+	0x8600:                  "Positioning controller",
+	0x8611:                  "Following error",
+	0x8612:                  "Reference limit",
+	ErrorExternal:           "External error",
+	ErrorAdditionalFunction: "Additional functions",
+	ErrorDeviceSpecific:     "Device specific",
+}
+
+// Description returns the description of the error code.
+// Codes without own description are described by their error class, e.g. 0x3105 by "Mains voltage".
+func (code ErrorCode) Description() string {
+	for _, mask := range []ErrorCode{0xFFFF, 0xFFF0, 0xFF00, 0xF000} {
+		if code&mask == ErrorResetOrNoError && code != ErrorResetOrNoError {
+			break
+		}
+
+		if description, ok := descriptions[code&mask]; ok {
+			return description
+		}
+	}
+
+	return "Unknown error"
+}
+
+func (code ErrorCode) String() string {
+	return fmt.Sprintf("%04X %s", uint16(code), code.Description())
+}
+
+// ErrorRegister is the error register of the device (0x1001)
+type ErrorRegister uint8
+
+const (
+	RegisterGeneric       ErrorRegister = 1 << 0
+	RegisterCurrent       ErrorRegister = 1 << 1
+	RegisterVoltage       ErrorRegister = 1 << 2
+	RegisterTemperature   ErrorRegister = 1 << 3
+	RegisterCommunication ErrorRegister = 1 << 4
+	RegisterDeviceProfile ErrorRegister = 1 << 5
+	RegisterManufacturer  ErrorRegister = 1 << 7
+)
+
+var registerNames = []struct {
+	bit  ErrorRegister
+	name string
+}{
+	{RegisterGeneric, "Generic"},
+	{RegisterCurrent, "Current"},
+	{RegisterVoltage, "Voltage"},
+	{RegisterTemperature, "Temperature"},
+	{RegisterCommunication, "Communication"},
+	{RegisterDeviceProfile, "Device profile"},
+	{RegisterManufacturer, "Manufacturer"},
+}
+
+func (register ErrorRegister) String() string {
+	var names []string
+	for _, r := range registerNames {
+		if register&r.bit != 0 {
+			names = append(names, r.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "No error"
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,256 @@
+package emcy
+
+import (
+	"encoding/binary"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultHistorySize is the number of errors kept in the pre-defined error field if no size is set
+	DefaultHistorySize = 8
+
+	indexErrorRegister  = 0x1001
+	indexErrorField     = 0x1003
+	indexInhibitTime    = 0x1015
+	inhibitTimeUnit     = 100 * time.Microsecond
+	maxHistorySize      = 0xFE
+	errorFieldEntrySize = 4
+)
+
+// Producer reports the errors of a local node with emergency messages.
+// It maintains the error register (0x1001) and the pre-defined error field (0x1003) in the object dictionary,
+// so they can be read through an SDO server.
+type Producer struct {
+	NodeID uint8
+	// InhibitTime is the minimal time between two emergency messages (0x1015),
+	// messages are delayed until the inhibit time passed
+	InhibitTime time.Duration
+	// HistorySize is the number of errors kept in the pre-defined error field, 0 uses DefaultHistorySize.
+	// If the object dictionary already contains 0x1003 its size is used.
+	HistorySize uint8
+	// ObjectDictionary receives the error register and the pre-defined error field (optional)
+	ObjectDictionary *od.ObjectDictionary
+
+	bus *can.Bus
+
+	lock     sync.Mutex
+	register ErrorRegister
+	history  []uint32
+	pending  []Emergency
+	lastSent time.Time
+	timer    *time.Timer
+	running  bool
+}
+
+// Start adds the emergency objects to the object dictionary if they don't exist yet and enables the emergency messages.
+func (producer *Producer) Start(bus *can.Bus) {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.bus = bus
+	producer.running = true
+	if producer.HistorySize == 0 {
+		producer.HistorySize = DefaultHistorySize
+	}
+
+	if producer.HistorySize > maxHistorySize {
+		producer.HistorySize = maxHistorySize
+	}
+
+	if dictionary := producer.ObjectDictionary; dictionary != nil {
+		producer.addObjects(dictionary)
+		dictionary.OnWrite(indexErrorField, producer.writeErrorField)
+		dictionary.OnWrite(indexInhibitTime, producer.writeInhibitTime)
+		producer.updateObjects()
+	}
+}
+
+// Stop disables the emergency messages, pending messages are discarded.
+func (producer *Producer) Stop() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.running = false
+	producer.pending = nil
+	if producer.timer != nil {
+		producer.timer.Stop()
+		producer.timer = nil
+	}
+
+	if dictionary := producer.ObjectDictionary; dictionary != nil {
+		dictionary.OnWrite(indexErrorField, nil)
+		dictionary.OnWrite(indexInhibitTime, nil)
+	}
+}
+
+// Error reports an error: the error register bits are set, the error is added to the pre-defined error field
+// and an emergency message is sent.
+func (producer *Producer) Error(code ErrorCode, register ErrorRegister, manufacturerData [5]byte) {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	// The generic error bit is set as long as any error is present
+	producer.register |= register | RegisterGeneric
+
+	entry := uint32(code) | uint32(binary.LittleEndian.Uint16(manufacturerData[0:2]))<<16
+	producer.history = append([]uint32{entry}, producer.history...)
+	if len(producer.history) > int(producer.HistorySize) {
+		producer.history = producer.history[:producer.HistorySize]
+	}
+
+	producer.updateObjects()
+	producer.send(Emergency{
+		NodeID:           producer.NodeID,
+		ErrorCode:        code,
+		ErrorRegister:    producer.register,
+		ManufacturerData: manufacturerData,
+	})
+}
+
+// Reset clears the error register and sends the error reset emergency message.
+// The pre-defined error field is kept.
+func (producer *Producer) Reset() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.register = 0
+	producer.updateObjects()
+	producer.send(Emergency{NodeID: producer.NodeID, ErrorCode: ErrorResetOrNoError})
+}
+
+// ErrorRegister returns the current error register.
+func (producer *Producer) ErrorRegister() ErrorRegister {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	return producer.register
+}
+
+// History returns the entries of the pre-defined error field, the newest first.
+// Bits 0-15 contain the error code, bits 16-31 the first two bytes of the manufacturer data.
+func (producer *Producer) History() []uint32 {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	return append([]uint32{}, producer.history...)
+}
+
+// ClearHistory clears the pre-defined error field.
+func (producer *Producer) ClearHistory() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.history = nil
+	producer.updateObjects()
+}
+
+// send publishes the emergency or queues it until the inhibit time passed, the lock must be held
+func (producer *Producer) send(emergency Emergency) {
+	if !producer.running {
+		return
+	}
+
+	producer.pending = append(producer.pending, emergency)
+	if producer.timer == nil {
+		producer.flush()
+	}
+}
+
+// flush publishes the pending emergencies respecting the inhibit time, the lock must be held
+func (producer *Producer) flush() {
+	producer.timer = nil
+	for len(producer.pending) > 0 && producer.running {
+		if wait := producer.InhibitTime - time.Since(producer.lastSent); !producer.lastSent.IsZero() && wait > 0 {
+			producer.timer = time.AfterFunc(wait, func() {
+				producer.lock.Lock()
+				defer producer.lock.Unlock()
+
+				producer.flush()
+			})
+			return
+		}
+
+		emergency := producer.pending[0]
+		producer.pending = producer.pending[1:]
+		producer.lastSent = time.Now()
+		_ = producer.bus.PublishMinDuration(emergency.Frame().CANFrame(), 0)
+	}
+}
+
+// addObjects adds the emergency objects which don't exist yet
+func (producer *Producer) addObjects(dictionary *od.ObjectDictionary) {
+	if dictionary.Object(indexErrorRegister) == nil {
+		dictionary.Add(od.NewVariable(indexErrorRegister, "Error register", sdo.DATA_TYPE_UNSIGNED_8, od.ACCESS_TYPE_RO, []byte{0}))
+	}
+
+	// An existing object without sub indexes is replaced
+	if object := dictionary.Object(indexErrorField); object != nil && len(object.SubIndexes()) > 0 {
+		producer.HistorySize = uint8(len(object.SubIndexes()) - 1)
+	} else {
+		object = od.NewArray(indexErrorField, "Pre-defined error field", sdo.DATA_TYPE_UNSIGNED_32, od.ACCESS_TYPE_RO, producer.HistorySize)
+
+		// Sub index 0 contains the number of errors, writing 0 clears the history
+		object.AddSubIndex(&od.Variable{
+			Name:         "Number of errors",
+			SubIndex:     0,
+			DataType:     sdo.DATA_TYPE_UNSIGNED_8,
+			AccessType:   od.ACCESS_TYPE_RW,
+			DefaultValue: []byte{0},
+		})
+		dictionary.Add(object)
+	}
+
+	if dictionary.Object(indexInhibitTime) == nil {
+		inhibitTime := make([]byte, 2)
+		binary.LittleEndian.PutUint16(inhibitTime, uint16(producer.InhibitTime/inhibitTimeUnit))
+		dictionary.Add(od.NewVariable(indexInhibitTime, "Inhibit time EMCY", sdo.DATA_TYPE_UNSIGNED_16, od.ACCESS_TYPE_RW, inhibitTime))
+	} else if value, abortCode := dictionary.Value(canopen.NewObjectIndex(indexInhibitTime, 0)); abortCode == canopen.NO_ERROR && len(value) == 2 {
+		producer.InhibitTime = time.Duration(binary.LittleEndian.Uint16(value)) * inhibitTimeUnit
+	}
+}
+
+// updateObjects writes the error register and the pre-defined error field to the object dictionary, the lock must be held
+func (producer *Producer) updateObjects() {
+	dictionary := producer.ObjectDictionary
+	if dictionary == nil {
+		return
+	}
+
+	dictionary.SetValue(canopen.NewObjectIndex(indexErrorRegister, 0), []byte{byte(producer.register)})
+	dictionary.SetValue(canopen.NewObjectIndex(indexErrorField, 0), []byte{byte(len(producer.history))})
+	for i := 0; i < int(producer.HistorySize); i++ {
+		entry := make([]byte, errorFieldEntrySize)
+		if i < len(producer.history) {
+			binary.LittleEndian.PutUint32(entry, producer.history[i])
+		}
+
+		dictionary.SetValue(canopen.NewObjectIndex(indexErrorField, uint8(i+1)), entry)
+	}
+}
+
+// writeErrorField clears the history if 0 is written to sub index 0, other values are rejected
+func (producer *Producer) writeErrorField(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+	if objectIndex.SubIndex != 0 {
+		return canopen.NO_ERROR
+	}
+
+	if data[0] != 0 {
+		return canopen.SDO_ERR_VALUE_RANGE
+	}
+
+	producer.ClearHistory()
+	return canopen.NO_ERROR
+}
+
+func (producer *Producer) writeInhibitTime(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.InhibitTime = time.Duration(binary.LittleEndian.Uint16(data)) * inhibitTimeUnit
+	return canopen.NO_ERROR
+}
@@ -0,0 +1,121 @@
+package emcy
+
+import (
+	"bytes"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProducer(t *testing.T) {
+	a, b := net.Pipe()
+	producerBus := can.NewBus(can.NewReadWriteCloser(a), "producer")
+	consumerBus := can.NewBus(can.NewReadWriteCloser(b), "consumer")
+	go producerBus.ConnectAndPublish()
+	go consumerBus.ConnectAndPublish()
+	defer producerBus.Disconnect()
+
+	sub := canopen.Subscribe(consumerBus, canopen.MessageTypeEMCY+5, 10)
+	defer sub.Close()
+
+	dictionary := od.NewObjectDictionary()
+	producer := &Producer{
+		NodeID:           5,
+		InhibitTime:      50 * time.Millisecond,
+		HistorySize:      2,
+		ObjectDictionary: dictionary,
+	}
+	producer.Start(producerBus)
+	defer producer.Stop()
+
+	expect := func(code ErrorCode, register ErrorRegister) time.Time {
+		frame, err := sub.Receive(time.Second)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		emergency, err := Decode(frame)
+		if err != nil || emergency.ErrorCode != code || emergency.ErrorRegister != register {
+			t.Log("Unexpected emergency", emergency, err)
+			t.FailNow()
+		}
+
+		return time.Now()
+	}
+
+	read := func(index uint16, subIndex uint8) []byte {
+		data, abortCode := dictionary.Read(canopen.NewObjectIndex(index, subIndex))
+		if abortCode != canopen.NO_ERROR {
+			t.Log("Unexpected abort code", abortCode)
+			t.FailNow()
+		}
+
+		return data
+	}
+
+	producer.Error(0x4210, RegisterTemperature, [5]byte{0x34, 0x12})
+	producer.Error(0x3120, RegisterVoltage, [5]byte{})
+	producer.Error(0x8130, RegisterCommunication, [5]byte{})
+	first := expect(0x4210, RegisterGeneric|RegisterTemperature)
+	second := expect(0x3120, RegisterGeneric|RegisterTemperature|RegisterVoltage)
+	expect(0x8130, RegisterGeneric|RegisterTemperature|RegisterVoltage|RegisterCommunication)
+
+	if second.Sub(first) < 40*time.Millisecond {
+		t.Log("Inhibit time not respected", second.Sub(first))
+		t.FailNow()
+	}
+
+	// The history keeps the newest errors
+	if !bytes.Equal(read(0x1003, 0), []byte{2}) || !bytes.Equal(read(0x1003, 1), []byte{0x30, 0x81, 0, 0}) || !bytes.Equal(read(0x1003, 2), []byte{0x20, 0x31, 0, 0}) {
+		t.Log("Unexpected pre-defined error field", producer.History())
+		t.FailNow()
+	}
+
+	if !bytes.Equal(read(0x1001, 0), []byte{0x1D}) {
+		t.Log("Unexpected error register", read(0x1001, 0))
+		t.FailNow()
+	}
+
+	producer.Reset()
+	expect(ErrorResetOrNoError, 0)
+
+	if abortCode := dictionary.Write(canopen.NewObjectIndex(0x1003, 0), []byte{1}); abortCode != canopen.SDO_ERR_VALUE_RANGE {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if abortCode := dictionary.Write(canopen.NewObjectIndex(0x1003, 0), []byte{0}); abortCode != canopen.NO_ERROR {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if len(producer.History()) != 0 || !bytes.Equal(read(0x1003, 0), []byte{0}) || !bytes.Equal(read(0x1003, 1), []byte{0, 0, 0, 0}) {
+		t.Log("History was not cleared", producer.History())
+		t.FailNow()
+	}
+
+	// 0x1015 is written in multiples of 100 µs
+	if abortCode := dictionary.Write(canopen.NewObjectIndex(0x1015, 0), []byte{0x0A, 0x00}); abortCode != canopen.NO_ERROR || producer.InhibitTime != time.Millisecond {
+		t.Log("Inhibit time not changed", abortCode, producer.InhibitTime)
+		t.FailNow()
+	}
+}
+
+func TestProducerEmptyErrorField(t *testing.T) {
+	// An empty pre-defined error field is replaced
+	dictionary := od.NewObjectDictionary()
+	dictionary.Add(od.NewObject(0x1003, "Pre-defined error field", od.OBJECT_TYPE_ARRAY))
+
+	producer := &Producer{NodeID: 5, HistorySize: 4, ObjectDictionary: dictionary}
+	producer.Start(can.NewBus(nil, "test"))
+	defer producer.Stop()
+
+	if producer.HistorySize != 4 || len(dictionary.Object(0x1003).SubIndexes()) != 5 {
+		t.Log("Unexpected history size", producer.HistorySize)
+		t.FailNow()
+	}
+}
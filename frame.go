@@ -44,6 +44,16 @@ func (frm Frame) NodeID() uint8 {
 	return uint8(frm.CobID & MaskNodeID)
 }
 
+// IsSync returns true for SYNC messages.
+func (frm Frame) IsSync() bool {
+	return frm.CobID == MessageTypeSync
+}
+
+// IsEmergency returns true for emergency messages, which share the message type with SYNC but have a node id.
+func (frm Frame) IsEmergency() bool {
+	return frm.MessageType() == MessageTypeEMCY && frm.NodeID() != 0
+}
+
 // CANFrame returns a CAN frame representing the CANopen frame.
 //
 // CANopen frames are encoded as follows:
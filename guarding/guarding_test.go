@@ -0,0 +1,98 @@
+package guarding
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGuarding(t *testing.T) {
+	a, b := net.Pipe()
+	masterBus := can.NewBus(can.NewReadWriteCloser(a), "master")
+	slaveBus := can.NewBus(can.NewReadWriteCloser(b), "slave")
+	go masterBus.ConnectAndPublish()
+	go slaveBus.ConnectAndPublish()
+	defer masterBus.Disconnect()
+	defer slaveBus.Disconnect()
+
+	lost := make(chan bool, 10)
+	slave := &Slave{
+		NodeID:         5,
+		State:          func() canopen.NMTState { return canopen.Operational },
+		GuardTime:      20 * time.Millisecond,
+		LifeTimeFactor: 3,
+		OnLifeGuarding: func(l bool) { lost <- l },
+	}
+	slave.Start(slaveBus)
+	// Starting twice must not answer the requests twice
+	slave.Start(slaveBus)
+
+	events := make(chan Event, 10)
+	master := NewMaster(masterBus, func(event Event) {
+		events <- event
+	})
+	defer master.Close()
+
+	expect := func(eventType EventType, state canopen.NMTState) {
+		select {
+		case event := <-events:
+			if event.Type != eventType || event.NodeID != 5 || event.State != state {
+				t.Log("Unexpected event", event.Type, event.NodeID, event.State)
+				t.FailNow()
+			}
+		case <-time.After(time.Second):
+			t.Log("Missing event", eventType)
+			t.FailNow()
+		}
+	}
+
+	master.Guard(5, 20*time.Millisecond, 3)
+	expect(EventStateChanged, canopen.Operational)
+
+	// Several responses with alternating toggle bit don't emit events
+	time.Sleep(100 * time.Millisecond)
+	if status, _ := master.Status(5); status.Error || status.State != canopen.Operational {
+		t.Log("Unexpected status", status)
+		t.FailNow()
+	}
+	select {
+	case event := <-events:
+		t.Log("Unexpected event", event.Type)
+		t.FailNow()
+	default:
+	}
+
+	slave.Stop()
+	expect(EventGuardingError, canopen.Operational)
+
+	slave.Start(slaveBus)
+	// The restarted slave begins with toggle bit 0 again
+	select {
+	case event := <-events:
+		if event.Type == EventToggleError {
+			expect(EventRecovered, canopen.Operational)
+		} else if event.Type != EventRecovered {
+			t.Log("Unexpected event", event.Type)
+			t.FailNow()
+		}
+	case <-time.After(time.Second):
+		t.Log("Missing event", EventRecovered)
+		t.FailNow()
+	}
+
+	// Life guarding on the slave side
+	master.Unguard(5)
+	select {
+	case l := <-lost:
+		if !l {
+			t.Log("Unexpected life guarding event")
+			t.FailNow()
+		}
+	case <-time.After(time.Second):
+		t.Log("Missing life guarding event")
+		t.FailNow()
+	}
+	slave.Stop()
+}
@@ -0,0 +1,267 @@
+package guarding
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType describes what happened to a guarded node
+type EventType uint8
+
+const (
+	// EventStateChanged is emitted when the guarding response contains a new state
+	EventStateChanged EventType = iota
+	// EventGuardingError is emitted when the node did not respond within the life time (guard time * life time factor)
+	EventGuardingError
+	// EventToggleError is emitted when the toggle bit of the response did not alternate
+	EventToggleError
+	// EventRecovered is emitted when the node responds correctly again after a guarding error
+	EventRecovered
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventStateChanged:
+		return "State changed"
+	case EventGuardingError:
+		return "Guarding error"
+	case EventToggleError:
+		return "Toggle error"
+	case EventRecovered:
+		return "Guarding recovered"
+	}
+
+	return "Unknown"
+}
+
+// Event is emitted when the guarding status of a node changes.
+type Event struct {
+	Type   EventType
+	NodeID uint8
+	// State is the last known state of the node
+	State canopen.NMTState
+	Time  time.Time
+}
+
+// Status is the guarding status of a node.
+type Status struct {
+	NodeID uint8
+	State  canopen.NMTState
+	// LastSeen is the time of the last valid response
+	LastSeen time.Time
+	// GuardTime is the interval in which the node is polled (0x100C)
+	GuardTime time.Duration
+	// LifeTimeFactor is the number of guard times without response after which a guarding error occurs (0x100D)
+	LifeTimeFactor uint8
+	// Error is set while the node does not respond correctly
+	Error bool
+}
+
+type node struct {
+	status  Status
+	toggle  bool
+	started bool
+	stop    chan struct{}
+}
+
+// Master polls the guarded nodes with remote transmit requests and validates their responses.
+type Master struct {
+	bus     *can.Bus
+	handler can.Handler
+	onEvent func(Event)
+
+	lock  sync.Mutex
+	nodes map[uint8]*node
+}
+
+// NewMaster returns a master which listens to the guarding responses of the bus.
+// onEvent is called for every event, it must not block because it is called while the bus dispatches frames.
+func NewMaster(bus *can.Bus, onEvent func(Event)) *Master {
+	master := &Master{
+		bus:     bus,
+		onEvent: onEvent,
+		nodes:   map[uint8]*node{},
+	}
+
+	master.handler = can.NewHandler(master.handle)
+	bus.Subscribe(master.handler)
+
+	return master
+}
+
+// Close stops guarding all nodes.
+func (master *Master) Close() {
+	master.bus.Unsubscribe(master.handler)
+
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	for nodeID := range master.nodes {
+		master.unguard(nodeID)
+	}
+}
+
+// Guard starts polling a node every guard time.
+// A guarding error is raised if the node does not respond within guard time * life time factor.
+func (master *Master) Guard(nodeID uint8, guardTime time.Duration, lifeTimeFactor uint8) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	master.unguard(nodeID)
+	if guardTime <= 0 || lifeTimeFactor == 0 {
+		return
+	}
+
+	n := &node{
+		status: Status{
+			NodeID:         nodeID,
+			GuardTime:      guardTime,
+			LifeTimeFactor: lifeTimeFactor,
+		},
+		stop: make(chan struct{}),
+	}
+	master.nodes[nodeID] = n
+
+	go master.poll(n)
+}
+
+// Unguard stops polling a node.
+func (master *Master) Unguard(nodeID uint8) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	master.unguard(nodeID)
+}
+
+// Status returns the guarding status of a node.
+func (master *Master) Status(nodeID uint8) (Status, bool) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	if n, ok := master.nodes[nodeID]; ok {
+		return n.status, true
+	}
+
+	return Status{}, false
+}
+
+// Statuses returns the guarding status of all guarded nodes ordered by node id.
+func (master *Master) Statuses() []Status {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	statuses := make([]Status, 0, len(master.nodes))
+	for _, n := range master.nodes {
+		statuses = append(statuses, n.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NodeID < statuses[j].NodeID
+	})
+
+	return statuses
+}
+
+func (master *Master) unguard(nodeID uint8) {
+	if n, ok := master.nodes[nodeID]; ok {
+		close(n.stop)
+		delete(master.nodes, nodeID)
+	}
+}
+
+func (master *Master) poll(n *node) {
+	ticker := time.NewTicker(n.status.GuardTime)
+	defer ticker.Stop()
+
+	lifeTime := n.status.GuardTime * time.Duration(n.status.LifeTimeFactor)
+	start := time.Now()
+	for {
+		// Remote transmit request, the data length is the expected length of the response
+		frame := canopen.Frame{
+			CobID: canopen.MessageTypeHeartbeat + uint16(n.status.NodeID),
+			Rtr:   true,
+			Data:  make([]byte, 1),
+		}
+		_ = master.bus.PublishMinDuration(frame.CANFrame(), 0)
+
+		select {
+		case <-ticker.C:
+		case <-n.stop:
+			return
+		}
+
+		master.lock.Lock()
+		lastSeen := n.status.LastSeen
+		if lastSeen.IsZero() {
+			lastSeen = start
+		}
+
+		var events []Event
+		if !n.status.Error && time.Since(lastSeen) >= lifeTime {
+			n.status.Error = true
+			events = append(events, Event{Type: EventGuardingError, NodeID: n.status.NodeID, State: n.status.State, Time: time.Now()})
+		}
+		master.lock.Unlock()
+
+		master.emit(events...)
+	}
+}
+
+func (master *Master) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.MessageType() != canopen.MessageTypeHeartbeat || frm.Length != 1 {
+		return
+	}
+
+	master.lock.Lock()
+	n, ok := master.nodes[frame.NodeID()]
+	if !ok {
+		master.lock.Unlock()
+		return
+	}
+
+	toggle := frame.Data[0]&0x80 != 0
+	state := canopen.NMTState(frame.Data[0] & 0x7F)
+	now := time.Now()
+
+	// The first response has the toggle bit 0 and alternates afterwards
+	var events []Event
+	if toggle != n.toggle {
+		events = append(events, Event{Type: EventToggleError, NodeID: n.status.NodeID, State: state, Time: now})
+		n.toggle = !toggle
+		master.lock.Unlock()
+
+		master.emit(events...)
+		return
+	}
+	n.toggle = !toggle
+
+	if n.status.Error {
+		n.status.Error = false
+		events = append(events, Event{Type: EventRecovered, NodeID: n.status.NodeID, State: state, Time: now})
+	}
+
+	if !n.started || n.status.State != state {
+		events = append(events, Event{Type: EventStateChanged, NodeID: n.status.NodeID, State: state, Time: now})
+	}
+
+	n.started = true
+	n.status.State = state
+	n.status.LastSeen = now
+	master.lock.Unlock()
+
+	master.emit(events...)
+}
+
+func (master *Master) emit(events ...Event) {
+	if master.onEvent == nil {
+		return
+	}
+
+	for _, event := range events {
+		master.onEvent(event)
+	}
+}
@@ -0,0 +1,144 @@
+package guarding
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// Slave answers the guarding requests of the master for a local node.
+// If the guard time (0x100C) and the life time factor (0x100D) are set,
+// it also monitors the requests of the master (life guarding).
+type Slave struct {
+	NodeID uint8
+	// State returns the current NMT state of the node, e.g. heartbeat.Producer.State
+	State func() canopen.NMTState
+	// GuardTime is the expected interval of the guarding requests (0x100C), 0 disables life guarding
+	GuardTime time.Duration
+	// LifeTimeFactor is the number of guard times without request after which a life guarding event occurs (0x100D)
+	LifeTimeFactor uint8
+	// OnLifeGuarding is called with true if the master stopped guarding the node
+	// and with false when the guarding requests are received again (optional)
+	OnLifeGuarding func(lost bool)
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock    sync.Mutex
+	toggle  bool
+	lost    bool
+	timer   *time.Timer
+	running bool
+}
+
+// Start answers the guarding requests of the bus.
+// Starting a running slave has no effect.
+func (slave *Slave) Start(bus *can.Bus) {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	if slave.running {
+		return
+	}
+
+	slave.bus = bus
+	slave.toggle = false
+	slave.lost = false
+	slave.running = true
+
+	slave.handler = can.NewHandler(slave.handle)
+	bus.Subscribe(slave.handler)
+}
+
+// Stop stops answering the guarding requests.
+func (slave *Slave) Stop() {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	if !slave.running {
+		return
+	}
+
+	slave.bus.Unsubscribe(slave.handler)
+	if slave.timer != nil {
+		slave.timer.Stop()
+		slave.timer = nil
+	}
+	slave.running = false
+}
+
+func (slave *Slave) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskErr) != 0 {
+		return
+	}
+
+	// The toggle bit starts with 0 again after a reset
+	if !frame.Rtr && frame.CobID == canopen.MessageTypeNMT && frm.Length == 2 {
+		command := canopen.NMTCommand(frame.Data[0])
+		if nodeID := frame.Data[1]; (nodeID == 0 || nodeID == slave.NodeID) && (command == canopen.GoToResetNode || command == canopen.GoToResetCommunication) {
+			slave.lock.Lock()
+			slave.toggle = false
+			slave.lock.Unlock()
+		}
+
+		return
+	}
+
+	if !frame.Rtr || frame.CobID != canopen.MessageTypeHeartbeat+uint16(slave.NodeID) {
+		return
+	}
+
+	state := canopen.PreOperational
+	if slave.State != nil {
+		state = slave.State()
+	}
+
+	slave.lock.Lock()
+	data := byte(state) & 0x7F
+	if slave.toggle {
+		data |= 0x80
+	}
+	slave.toggle = !slave.toggle
+
+	recovered := slave.lost
+	slave.lost = false
+	slave.restartLifeGuarding()
+	slave.lock.Unlock()
+
+	response := canopen.NewFrame(canopen.MessageTypeHeartbeat+uint16(slave.NodeID), []byte{data})
+	_ = slave.bus.PublishMinDuration(response.CANFrame(), 0)
+
+	if recovered && slave.OnLifeGuarding != nil {
+		slave.OnLifeGuarding(false)
+	}
+}
+
+// restartLifeGuarding restarts the life time after a request, the lock must be held
+func (slave *Slave) restartLifeGuarding() {
+	if slave.GuardTime <= 0 || slave.LifeTimeFactor == 0 {
+		return
+	}
+
+	lifeTime := slave.GuardTime * time.Duration(slave.LifeTimeFactor)
+	if slave.timer == nil {
+		slave.timer = time.AfterFunc(lifeTime, slave.lifeGuardingEvent)
+	} else {
+		slave.timer.Reset(lifeTime)
+	}
+}
+
+func (slave *Slave) lifeGuardingEvent() {
+	slave.lock.Lock()
+	if !slave.running || slave.lost {
+		slave.lock.Unlock()
+		return
+	}
+	slave.lost = true
+	slave.lock.Unlock()
+
+	if slave.OnLifeGuarding != nil {
+		slave.OnLifeGuarding(true)
+	}
+}
@@ -0,0 +1,241 @@
+package heartbeat
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventType describes what happened to a monitored node
+type EventType uint8
+
+const (
+	// EventBootUp is emitted when a node sends the boot-up message
+	EventBootUp EventType = iota
+	// EventStateChanged is emitted when the heartbeat contains a new state
+	EventStateChanged
+	// EventTimeout is emitted when the heartbeat was not received within the consumer heartbeat time
+	EventTimeout
+	// EventRecovered is emitted when the heartbeat is received again after a timeout
+	EventRecovered
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventBootUp:
+		return "Boot-up"
+	case EventStateChanged:
+		return "State changed"
+	case EventTimeout:
+		return "Heartbeat timeout"
+	case EventRecovered:
+		return "Heartbeat recovered"
+	}
+
+	return "Unknown"
+}
+
+// Event is emitted by the consumer when the status of a node changes.
+type Event struct {
+	Type   EventType
+	NodeID uint8
+	// State is the last known state of the node
+	State canopen.NMTState
+	Time  time.Time
+}
+
+// Status is the heartbeat status of a node.
+type Status struct {
+	NodeID uint8
+	State  canopen.NMTState
+	// LastSeen is the time of the last heartbeat
+	LastSeen time.Time
+	// ConsumerTime is the time in which a heartbeat is expected (0 = not monitored)
+	ConsumerTime time.Duration
+	// Timeout is set if the heartbeat was not received within the consumer time
+	Timeout bool
+}
+
+type node struct {
+	status Status
+	timer  *time.Timer
+}
+
+// Consumer monitors the heartbeat messages of the nodes on a bus.
+type Consumer struct {
+	bus     *can.Bus
+	handler can.Handler
+	onEvent func(Event)
+
+	lock  sync.Mutex
+	nodes map[uint8]*node
+}
+
+// NewConsumer returns a consumer which listens to the heartbeat messages of the bus.
+// onEvent is called for every event, it must not block because it is called while the bus dispatches frames.
+func NewConsumer(bus *can.Bus, onEvent func(Event)) *Consumer {
+	consumer := &Consumer{
+		bus:     bus,
+		onEvent: onEvent,
+		nodes:   map[uint8]*node{},
+	}
+
+	consumer.handler = can.NewHandler(consumer.handle)
+	bus.Subscribe(consumer.handler)
+
+	return consumer
+}
+
+// Close stops listening to the heartbeat messages.
+func (consumer *Consumer) Close() {
+	consumer.bus.Unsubscribe(consumer.handler)
+
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	for _, n := range consumer.nodes {
+		if n.timer != nil {
+			n.timer.Stop()
+		}
+	}
+}
+
+// Monitor sets the consumer heartbeat time of a node, 0 disables the monitoring.
+// The monitoring starts with the first heartbeat of the node.
+func (consumer *Consumer) Monitor(nodeID uint8, consumerTime time.Duration) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	n := consumer.node(nodeID)
+	n.status.ConsumerTime = consumerTime
+
+	if n.timer != nil {
+		n.timer.Stop()
+		n.timer = nil
+	}
+
+	if consumerTime > 0 && !n.status.LastSeen.IsZero() && !n.status.Timeout {
+		n.timer = time.AfterFunc(consumerTime, func() { consumer.timeout(nodeID) })
+	}
+}
+
+// MonitorEntry sets the consumer heartbeat time as encoded in a sub index of object 0x1016
+// (bits 16-23 node id, bits 0-15 heartbeat time in ms).
+func (consumer *Consumer) MonitorEntry(entry uint32) {
+	nodeID := uint8(entry >> 16)
+	if nodeID == 0 || nodeID > canopen.MaxNodeID {
+		return
+	}
+
+	consumer.Monitor(nodeID, time.Duration(entry&0xFFFF)*time.Millisecond)
+}
+
+// Status returns the heartbeat status of a node.
+func (consumer *Consumer) Status(nodeID uint8) (Status, bool) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if n, ok := consumer.nodes[nodeID]; ok {
+		return n.status, true
+	}
+
+	return Status{}, false
+}
+
+// Statuses returns the heartbeat status of all monitored or seen nodes ordered by node id.
+func (consumer *Consumer) Statuses() []Status {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	statuses := make([]Status, 0, len(consumer.nodes))
+	for _, n := range consumer.nodes {
+		statuses = append(statuses, n.status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NodeID < statuses[j].NodeID
+	})
+
+	return statuses
+}
+
+func (consumer *Consumer) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.MessageType() != canopen.MessageTypeHeartbeat || frame.NodeID() == 0 || frm.Length != 1 {
+		return
+	}
+
+	nodeID := frame.NodeID()
+	state := canopen.NMTState(frame.Data[0] & 0x7F)
+	now := time.Now()
+
+	var events []Event
+	consumer.lock.Lock()
+	n := consumer.node(nodeID)
+	isFirst := n.status.LastSeen.IsZero()
+
+	if n.status.Timeout {
+		n.status.Timeout = false
+		events = append(events, Event{Type: EventRecovered, NodeID: nodeID, State: state, Time: now})
+	}
+
+	if state == canopen.BootUp {
+		events = append(events, Event{Type: EventBootUp, NodeID: nodeID, State: state, Time: now})
+	} else if isFirst || n.status.State != state {
+		events = append(events, Event{Type: EventStateChanged, NodeID: nodeID, State: state, Time: now})
+	}
+
+	n.status.State = state
+	n.status.LastSeen = now
+
+	// Restart the monitoring
+	if n.status.ConsumerTime > 0 {
+		if n.timer == nil {
+			n.timer = time.AfterFunc(n.status.ConsumerTime, func() { consumer.timeout(nodeID) })
+		} else {
+			n.timer.Reset(n.status.ConsumerTime)
+		}
+	}
+	consumer.lock.Unlock()
+
+	consumer.emit(events...)
+}
+
+func (consumer *Consumer) timeout(nodeID uint8) {
+	consumer.lock.Lock()
+	n := consumer.node(nodeID)
+
+	// The heartbeat may have arrived while the timer fired
+	if n.status.Timeout || n.status.ConsumerTime == 0 || time.Since(n.status.LastSeen) < n.status.ConsumerTime {
+		consumer.lock.Unlock()
+		return
+	}
+
+	n.status.Timeout = true
+	event := Event{Type: EventTimeout, NodeID: nodeID, State: n.status.State, Time: time.Now()}
+	consumer.lock.Unlock()
+
+	consumer.emit(event)
+}
+
+func (consumer *Consumer) emit(events ...Event) {
+	if consumer.onEvent == nil {
+		return
+	}
+
+	for _, event := range events {
+		consumer.onEvent(event)
+	}
+}
+
+func (consumer *Consumer) node(nodeID uint8) *node {
+	n, ok := consumer.nodes[nodeID]
+	if !ok {
+		n = &node{status: Status{NodeID: nodeID}}
+		consumer.nodes[nodeID] = n
+	}
+
+	return n
+}
@@ -0,0 +1,60 @@
+package heartbeat
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"testing"
+	"time"
+)
+
+func publishHeartbeat(bus *can.Bus, nodeID uint8, state canopen.NMTState) {
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeHeartbeat+uint16(nodeID), []byte{byte(state)}).CANFrame())
+}
+
+func TestConsumer(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	events := make(chan Event, 10)
+	consumer := NewConsumer(bus, func(event Event) {
+		events <- event
+	})
+	defer consumer.Close()
+
+	consumer.MonitorEntry(0x00050032) // node 5, 50ms
+
+	expect := func(eventType EventType, state canopen.NMTState) {
+		select {
+		case event := <-events:
+			if event.Type != eventType || event.NodeID != 5 || event.State != state {
+				t.Log("Unexpected event", event.Type, event.NodeID, event.State)
+				t.FailNow()
+			}
+		case <-time.After(time.Second):
+			t.Log("Missing event", eventType)
+			t.FailNow()
+		}
+	}
+
+	publishHeartbeat(bus, 5, canopen.BootUp)
+	expect(EventBootUp, canopen.BootUp)
+
+	publishHeartbeat(bus, 5, canopen.PreOperational)
+	expect(EventStateChanged, canopen.PreOperational)
+
+	// The same state does not emit an event
+	publishHeartbeat(bus, 5, canopen.PreOperational)
+	expect(EventTimeout, canopen.PreOperational)
+
+	if status, _ := consumer.Status(5); !status.Timeout || status.ConsumerTime != 50*time.Millisecond {
+		t.Log("Unexpected status", status)
+		t.FailNow()
+	}
+
+	publishHeartbeat(bus, 5, canopen.Operational)
+	expect(EventRecovered, canopen.Operational)
+	expect(EventStateChanged, canopen.Operational)
+
+	if statuses := consumer.Statuses(); len(statuses) != 1 || statuses[0].Timeout || statuses[0].State != canopen.Operational {
+		t.Log("Unexpected statuses", statuses)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,173 @@
+package heartbeat
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// Producer announces the NMT state of a local node.
+// It sends the boot-up message on start, publishes its state every producer heartbeat time (0x1017)
+// and changes the state on NMT commands for the node.
+type Producer struct {
+	NodeID uint8
+	// ProducerTime is the heartbeat interval, 0 disables the heartbeat
+	ProducerTime time.Duration
+	// OnCommand is called for NMT commands addressed to the node (optional),
+	// e.g. to reset the application on GoToResetNode
+	OnCommand func(canopen.NMTCommand)
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock    sync.Mutex
+	state   canopen.NMTState
+	stop    chan struct{}
+	running bool
+}
+
+// Start sends the boot-up message, enters the pre-operational state and starts the heartbeat.
+// Starting a running producer has no effect.
+func (producer *Producer) Start(bus *can.Bus) error {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	if producer.running {
+		return nil
+	}
+
+	producer.bus = bus
+	if err := producer.publish(canopen.BootUp); err != nil {
+		return err
+	}
+
+	producer.state = canopen.PreOperational
+	producer.running = true
+	producer.restartHeartbeat()
+
+	producer.handler = can.NewHandler(producer.handle)
+	bus.Subscribe(producer.handler)
+
+	return nil
+}
+
+// Stop stops the heartbeat and ignores further NMT commands.
+func (producer *Producer) Stop() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	if !producer.running {
+		return
+	}
+
+	producer.bus.Unsubscribe(producer.handler)
+	producer.stopHeartbeat()
+	producer.running = false
+}
+
+// State returns the current NMT state of the node.
+func (producer *Producer) State() canopen.NMTState {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	return producer.state
+}
+
+// SetState changes the NMT state of the node, e.g. when the application detects an error.
+func (producer *Producer) SetState(state canopen.NMTState) {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.state = state
+}
+
+// SetProducerTime changes the heartbeat interval, e.g. when 0x1017 is written.
+func (producer *Producer) SetProducerTime(producerTime time.Duration) {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.ProducerTime = producerTime
+	if producer.running {
+		producer.restartHeartbeat()
+	}
+}
+
+func (producer *Producer) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.CobID != canopen.MessageTypeNMT || frm.Length != 2 {
+		return
+	}
+
+	// Node id 0 addresses all nodes
+	command := canopen.NMTCommand(frame.Data[0])
+	if nodeID := frame.Data[1]; nodeID != 0 && nodeID != producer.NodeID {
+		return
+	}
+
+	producer.lock.Lock()
+	switch command {
+	case canopen.GoToOperational:
+		producer.state = canopen.Operational
+	case canopen.GoToStopped:
+		producer.state = canopen.Stopped
+	case canopen.GoToPreOperation:
+		producer.state = canopen.PreOperational
+	case canopen.GoToResetNode, canopen.GoToResetCommunication:
+		// The node boots again and enters the pre-operational state
+		producer.state = canopen.PreOperational
+		_ = producer.publish(canopen.BootUp)
+		producer.restartHeartbeat()
+	default:
+		producer.lock.Unlock()
+		return
+	}
+	producer.lock.Unlock()
+
+	if producer.OnCommand != nil {
+		producer.OnCommand(command)
+	}
+}
+
+// restartHeartbeat starts the heartbeat with the current producer time, the lock must be held
+func (producer *Producer) restartHeartbeat() {
+	producer.stopHeartbeat()
+	if producer.ProducerTime <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	producer.stop = stop
+	go func(producerTime time.Duration) {
+		ticker := time.NewTicker(producerTime)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				producer.lock.Lock()
+				select {
+				case <-stop:
+				default:
+					_ = producer.publish(producer.state)
+				}
+				producer.lock.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}(producer.ProducerTime)
+}
+
+// stopHeartbeat stops the heartbeat, the lock must be held
+func (producer *Producer) stopHeartbeat() {
+	if producer.stop != nil {
+		close(producer.stop)
+		producer.stop = nil
+	}
+}
+
+func (producer *Producer) publish(state canopen.NMTState) error {
+	frame := canopen.NewFrame(canopen.MessageTypeHeartbeat+uint16(producer.NodeID), []byte{byte(state)})
+	return producer.bus.PublishMinDuration(frame.CANFrame(), 0)
+}
@@ -0,0 +1,110 @@
+package heartbeat
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProducer(t *testing.T) {
+	a, b := net.Pipe()
+	nodeBus := can.NewBus(can.NewReadWriteCloser(a), "node")
+	masterBus := can.NewBus(can.NewReadWriteCloser(b), "master")
+	go nodeBus.ConnectAndPublish()
+	go masterBus.ConnectAndPublish()
+	defer nodeBus.Disconnect()
+	defer masterBus.Disconnect()
+
+	heartbeats := canopen.Subscribe(masterBus, canopen.MessageTypeHeartbeat+5, 100)
+	defer heartbeats.Close()
+
+	commands := make(chan canopen.NMTCommand, 10)
+	producer := &Producer{
+		NodeID:       5,
+		ProducerTime: 20 * time.Millisecond,
+		OnCommand:    func(command canopen.NMTCommand) { commands <- command },
+	}
+
+	// expect waits for a heartbeat with the state, other states are skipped
+	expect := func(state canopen.NMTState) {
+		for {
+			frame, err := heartbeats.Receive(time.Second)
+			if err != nil {
+				t.Log("Missing state", state, err)
+				t.FailNow()
+			}
+
+			if canopen.NMTState(frame.Data[0]) == state {
+				return
+			}
+		}
+	}
+
+	send := func(command canopen.NMTCommand, nodeID uint8) {
+		_ = masterBus.Publish(canopen.NewFrame(canopen.MessageTypeNMT, []byte{byte(command), nodeID}).CANFrame())
+	}
+
+	if err := producer.Start(nodeBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// The boot-up message is followed by the heartbeat in the pre-operational state
+	if frame, err := heartbeats.Receive(time.Second); err != nil || frame.Data[0] != byte(canopen.BootUp) {
+		t.Log("Missing boot-up", frame.Data, err)
+		t.FailNow()
+	}
+	expect(canopen.PreOperational)
+	expect(canopen.PreOperational)
+
+	// A second start has no effect
+	if err := producer.Start(nodeBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	send(canopen.GoToOperational, 5)
+	expect(canopen.Operational)
+	if command := <-commands; command != canopen.GoToOperational || producer.State() != canopen.Operational {
+		t.Log("Unexpected command", command, producer.State())
+		t.FailNow()
+	}
+
+	// Commands for other nodes are ignored, node id 0 addresses all nodes
+	send(canopen.GoToStopped, 6)
+	send(canopen.GoToStopped, 0)
+	expect(canopen.Stopped)
+	if command := <-commands; command != canopen.GoToStopped || len(commands) != 0 {
+		t.Log("Unexpected command", command, len(commands))
+		t.FailNow()
+	}
+
+	// The node boots again on reset
+	send(canopen.GoToResetCommunication, 5)
+	expect(canopen.BootUp)
+	expect(canopen.PreOperational)
+	<-commands
+
+	// A producer time of 0 disables the heartbeat
+	producer.SetProducerTime(0)
+	time.Sleep(30 * time.Millisecond)
+	for len(heartbeats.C) > 0 {
+		<-heartbeats.C
+	}
+
+	if frame, err := heartbeats.Receive(80 * time.Millisecond); err == nil {
+		t.Log("Unexpected heartbeat", frame.Data)
+		t.FailNow()
+	}
+
+	// Commands are ignored after stop
+	producer.Stop()
+	send(canopen.GoToOperational, 5)
+	time.Sleep(50 * time.Millisecond)
+	if state := producer.State(); state != canopen.PreOperational || len(commands) != 0 {
+		t.Log("Command handled after stop", state, len(commands))
+		t.FailNow()
+	}
+}
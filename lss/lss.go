@@ -0,0 +1,203 @@
+// Package lss implements the layer setting services (CiA 305) to configure the node id and bit timing of nodes.
+package lss
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+)
+
+const (
+	// MasterCobID is the COB-ID of the requests of the LSS master
+	MasterCobID uint16 = 0x7E5
+	// SlaveCobID is the COB-ID of the responses of the LSS slaves
+	SlaveCobID uint16 = 0x7E4
+	// UnconfiguredNodeID is the node id of nodes which were not configured yet
+	UnconfiguredNodeID uint8 = 0xFF
+	// IdentityIndex is the index of the identity object, sub indexes 1-4 contain the LSS address
+	IdentityIndex uint16 = 0x1018
+)
+
+// Command specifiers
+const (
+	csSwitchStateGlobal     byte = 0x04
+	csConfigureNodeID       byte = 0x11
+	csConfigureBitTiming    byte = 0x13
+	csActivateBitTiming     byte = 0x15
+	csStoreConfiguration    byte = 0x17
+	csSwitchStateVendor     byte = 0x40
+	csSwitchStateProduct    byte = 0x41
+	csSwitchStateRevision   byte = 0x42
+	csSwitchStateSerial     byte = 0x43
+	csSwitchStateSelective  byte = 0x44
+	csIdentifySlave         byte = 0x4F
+	csFastscan              byte = 0x51
+	csInquireVendor         byte = 0x5A
+	csInquireProduct        byte = 0x5B
+	csInquireRevision       byte = 0x5C
+	csInquireSerial         byte = 0x5D
+	csInquireNodeID         byte = 0x5E
+	fastscanReset           byte = 0x80
+	fastscanSubIndexes      byte = 4
+	fastscanBits                 = 32
+	errorCodeSuccess        byte = 0
+	errorCodeNotSupported   byte = 1
+	errorCodeStorage        byte = 2
+	errorCodeImplementation byte = 0xFF
+	bitTimingTableSelector  byte = 0
+)
+
+// ErrTimeout is returned if no slave responded on time
+var ErrTimeout = errors.New("LSS timeout")
+
+// ErrNoSlave is returned by Fastscan if no unconfigured slave responded
+var ErrNoSlave = errors.New("no unconfigured LSS slave")
+
+// Mode is the LSS state of a slave
+type Mode uint8
+
+const (
+	// ModeWaiting is the normal state, only switch state and fastscan services are processed
+	ModeWaiting Mode = 0
+	// ModeConfiguration allows to configure the slave
+	ModeConfiguration Mode = 1
+)
+
+func (mode Mode) String() string {
+	switch mode {
+	case ModeWaiting:
+		return "Waiting"
+	case ModeConfiguration:
+		return "Configuration"
+	}
+
+	return "Unknown"
+}
+
+// Identity is the LSS address of a node, it corresponds to the identity object (0x1018 sub indexes 1-4).
+type Identity struct {
+	VendorID       uint32
+	ProductCode    uint32
+	RevisionNumber uint32
+	SerialNumber   uint32
+}
+
+// Field returns the part of the identity with the sub index (0 vendor id, 1 product code, 2 revision number, 3 serial number).
+func (identity Identity) Field(subIndex uint8) uint32 {
+	switch subIndex {
+	case 0:
+		return identity.VendorID
+	case 1:
+		return identity.ProductCode
+	case 2:
+		return identity.RevisionNumber
+	case 3:
+		return identity.SerialNumber
+	}
+
+	return 0
+}
+
+// SetField sets the part of the identity with the sub index.
+func (identity *Identity) SetField(subIndex uint8, value uint32) {
+	switch subIndex {
+	case 0:
+		identity.VendorID = value
+	case 1:
+		identity.ProductCode = value
+	case 2:
+		identity.RevisionNumber = value
+	case 3:
+		identity.SerialNumber = value
+	}
+}
+
+// ReadIdentity reads the identity from the identity object (0x1018) of an object dictionary.
+func ReadIdentity(dictionary *od.ObjectDictionary) (Identity, error) {
+	var identity Identity
+	for subIndex := uint8(1); subIndex <= 4; subIndex++ {
+		objectIndex := canopen.NewObjectIndex(IdentityIndex, subIndex)
+		data, abortCode := dictionary.Value(objectIndex)
+		if abortCode != canopen.NO_ERROR {
+			return Identity{}, fmt.Errorf("reading %s: %s", objectIndex.String(), canopen.GetAbortCodeText(abortCode))
+		} else if len(data) != 4 {
+			return Identity{}, fmt.Errorf("invalid length %d of %s", len(data), objectIndex.String())
+		}
+
+		identity.SetField(subIndex-1, binary.LittleEndian.Uint32(data))
+	}
+
+	return identity, nil
+}
+
+func (identity Identity) String() string {
+	return fmt.Sprintf("%08X:%08X:%08X:%08X", identity.VendorID, identity.ProductCode, identity.RevisionNumber, identity.SerialNumber)
+}
+
+// BitTiming is an index of the standard CiA bit timing table
+type BitTiming uint8
+
+const (
+	BitTiming1000K BitTiming = 0
+	BitTiming800K  BitTiming = 1
+	BitTiming500K  BitTiming = 2
+	BitTiming250K  BitTiming = 3
+	BitTiming125K  BitTiming = 4
+	BitTiming50K   BitTiming = 6
+	BitTiming20K   BitTiming = 7
+	BitTiming10K   BitTiming = 8
+	// BitTimingAuto enables the automatic bit rate detection
+	BitTimingAuto BitTiming = 9
+)
+
+var bitRates = map[BitTiming]int{
+	BitTiming1000K: 1000000,
+	BitTiming800K:  800000,
+	BitTiming500K:  500000,
+	BitTiming250K:  250000,
+	BitTiming125K:  125000,
+	BitTiming50K:   50000,
+	BitTiming20K:   20000,
+	BitTiming10K:   10000,
+}
+
+// BitRate returns the bit rate in bit/s or 0 for automatic or unknown bit timings.
+func (timing BitTiming) BitRate() int {
+	return bitRates[timing]
+}
+
+func (timing BitTiming) String() string {
+	if timing == BitTimingAuto {
+		return "Auto"
+	}
+
+	if rate, ok := bitRates[timing]; ok {
+		return fmt.Sprintf("%d kbit/s", rate/1000)
+	}
+
+	return "Unknown"
+}
+
+// ConfigurationError is returned if a slave rejected a configuration service.
+type ConfigurationError struct {
+	Service string
+	// Code is 1 if the service is not supported or the value is out of range
+	Code uint8
+	// SpecificCode is the implementation specific error if Code is 0xFF
+	SpecificCode uint8
+}
+
+func (err *ConfigurationError) Error() string {
+	switch err.Code {
+	case errorCodeNotSupported:
+		return fmt.Sprintf("LSS %s: not supported", err.Service)
+	case errorCodeStorage:
+		return fmt.Sprintf("LSS %s: storage media access error", err.Service)
+	case errorCodeImplementation:
+		return fmt.Sprintf("LSS %s: implementation specific error %d", err.Service, err.SpecificCode)
+	}
+
+	return fmt.Sprintf("LSS %s: error %d", err.Service, err.Code)
+}
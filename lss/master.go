@@ -0,0 +1,258 @@
+package lss
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the time the master waits for a response if no timeout is set
+const DefaultTimeout = 100 * time.Millisecond
+
+// Master sends LSS requests and waits for the responses of the slaves.
+// Only one service is executed at a time because the responses don't identify the slave.
+type Master struct {
+	// Timeout is the time to wait for a response, Fastscan also uses it to detect that no slave responded
+	Timeout time.Duration
+
+	bus  *can.Bus
+	lock sync.Mutex
+}
+
+// NewMaster returns a master which sends the requests on the bus.
+func NewMaster(bus *can.Bus) *Master {
+	return &Master{
+		Timeout: DefaultTimeout,
+		bus:     bus,
+	}
+}
+
+// SwitchStateGlobal switches all slaves to the mode.
+func (master *Master) SwitchStateGlobal(mode Mode) error {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	return master.send([]byte{csSwitchStateGlobal, byte(mode)})
+}
+
+// SwitchStateSelective switches the slave with the identity to the configuration mode.
+func (master *Master) SwitchStateSelective(identity Identity) error {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	sub := canopen.Subscribe(master.bus, SlaveCobID, 8)
+	defer sub.Close()
+
+	for i, cs := range []byte{csSwitchStateVendor, csSwitchStateProduct, csSwitchStateRevision, csSwitchStateSerial} {
+		if err := master.send(withValue(cs, identity.Field(uint8(i)))); err != nil {
+			return err
+		}
+	}
+
+	_, err := master.receive(sub, csSwitchStateSelective)
+	return err
+}
+
+// ConfigureNodeID sets the node id of the slave in configuration mode.
+// The node id 0xFF resets the slave to an unconfigured node.
+func (master *Master) ConfigureNodeID(nodeID uint8) error {
+	if (nodeID == 0 || nodeID > canopen.MaxNodeID) && nodeID != UnconfiguredNodeID {
+		return fmt.Errorf("invalid node id %d", nodeID)
+	}
+
+	return master.configure("configure node id", []byte{csConfigureNodeID, nodeID})
+}
+
+// ConfigureBitTiming sets the bit timing of the slave in configuration mode, it is used after ActivateBitTiming.
+func (master *Master) ConfigureBitTiming(timing BitTiming) error {
+	return master.configure("configure bit timing", []byte{csConfigureBitTiming, bitTimingTableSelector, byte(timing)})
+}
+
+// ActivateBitTiming makes all slaves in configuration mode use the configured bit timing.
+// The slaves stop sending for delay, switch the bit timing and wait for delay again before sending.
+func (master *Master) ActivateBitTiming(delay time.Duration) error {
+	milliseconds := delay.Milliseconds()
+	if milliseconds < 0 || milliseconds > 0xFFFF {
+		return fmt.Errorf("invalid switch delay %s", delay)
+	}
+
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	data := []byte{csActivateBitTiming, 0, 0}
+	binary.LittleEndian.PutUint16(data[1:], uint16(milliseconds))
+
+	return master.send(data)
+}
+
+// StoreConfiguration makes the slave in configuration mode store the configured node id and bit timing.
+func (master *Master) StoreConfiguration() error {
+	return master.configure("store configuration", []byte{csStoreConfiguration})
+}
+
+// InquireIdentity reads the identity of the slave in configuration mode.
+func (master *Master) InquireIdentity() (Identity, error) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	var identity Identity
+	for i, cs := range []byte{csInquireVendor, csInquireProduct, csInquireRevision, csInquireSerial} {
+		frame, err := master.request([]byte{cs}, cs)
+		if err != nil {
+			return Identity{}, err
+		}
+
+		identity.SetField(uint8(i), binary.LittleEndian.Uint32(frame.Data[1:5]))
+	}
+
+	return identity, nil
+}
+
+// InquireNodeID reads the node id of the slave in configuration mode.
+func (master *Master) InquireNodeID() (uint8, error) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	frame, err := master.request([]byte{csInquireNodeID}, csInquireNodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	return frame.Data[1], nil
+}
+
+// Fastscan finds the identity of an unconfigured slave with a binary search.
+// The found slave is in configuration mode afterwards, so it can be configured right away.
+// ErrNoSlave is returned if there is no unconfigured slave in waiting mode.
+func (master *Master) Fastscan() (Identity, error) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	found, err := master.fastscan(0, fastscanReset, 0, 0)
+	if err != nil {
+		return Identity{}, err
+	} else if !found {
+		return Identity{}, ErrNoSlave
+	}
+
+	var identity Identity
+	for subIndex := byte(0); subIndex < fastscanSubIndexes; subIndex++ {
+		// The slaves respond if the bits from 31 to the checked bit match
+		var value uint32
+		for bit := fastscanBits - 1; bit >= 0; bit-- {
+			found, err := master.fastscan(value, byte(bit), subIndex, subIndex)
+			if err != nil {
+				return Identity{}, err
+			}
+
+			if !found {
+				value |= 1 << bit
+			}
+		}
+
+		// The slave verifies the complete value and continues with the next sub index,
+		// after the last sub index it switches to the configuration mode
+		next := (subIndex + 1) % fastscanSubIndexes
+		if found, err := master.fastscan(value, 0, subIndex, next); err != nil {
+			return Identity{}, err
+		} else if !found {
+			return Identity{}, fmt.Errorf("LSS slave did not confirm fastscan value %08X of sub index %d", value, subIndex)
+		}
+
+		identity.SetField(subIndex, value)
+	}
+
+	return identity, nil
+}
+
+// fastscan sends a fastscan request and returns true if a slave responded, the lock must be held.
+// Several slaves may respond, so it waits the whole timeout to not take a late response for the next request.
+func (master *Master) fastscan(value uint32, bitChecked byte, subIndex byte, next byte) (bool, error) {
+	sub := canopen.Subscribe(master.bus, SlaveCobID, 8)
+	defer sub.Close()
+
+	data := withValue(csFastscan, value)
+	if err := master.send(append(data, bitChecked, subIndex, next)); err != nil {
+		return false, err
+	}
+
+	deadline := time.NewTimer(master.timeout())
+	defer deadline.Stop()
+
+	found := false
+	for {
+		select {
+		case frame := <-sub.C:
+			found = found || frame.Data[0] == csIdentifySlave
+		case <-deadline.C:
+			return found, nil
+		}
+	}
+}
+
+// configure sends a configuration request and validates the error code of the response
+func (master *Master) configure(service string, data []byte) error {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	frame, err := master.request(data, data[0])
+	if err != nil {
+		return err
+	}
+
+	if code := frame.Data[1]; code != errorCodeSuccess {
+		return &ConfigurationError{Service: service, Code: code, SpecificCode: frame.Data[2]}
+	}
+
+	return nil
+}
+
+// request sends a request and waits for the response with the command specifier, the lock must be held
+func (master *Master) request(data []byte, cs byte) (canopen.Frame, error) {
+	sub := canopen.Subscribe(master.bus, SlaveCobID, 8)
+	defer sub.Close()
+
+	if err := master.send(data); err != nil {
+		return canopen.Frame{}, err
+	}
+
+	return master.receive(sub, cs)
+}
+
+func (master *Master) receive(sub *canopen.Subscription, cs byte) (canopen.Frame, error) {
+	deadline := time.NewTimer(master.timeout())
+	defer deadline.Stop()
+
+	for {
+		select {
+		case frame := <-sub.C:
+			if len(frame.Data) == 8 && frame.Data[0] == cs {
+				return frame, nil
+			}
+		case <-deadline.C:
+			return canopen.Frame{}, ErrTimeout
+		}
+	}
+}
+
+func (master *Master) timeout() time.Duration {
+	if master.Timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return master.Timeout
+}
+
+// send publishes a request, unused bytes are reserved and 0
+func (master *Master) send(data []byte) error {
+	frame := canopen.NewFrame(MasterCobID, append(data, make([]byte, 8-len(data))...))
+	return master.bus.PublishMinDuration(frame.CANFrame(), 0)
+}
+
+// withValue returns the command specifier followed by the value
+func withValue(cs byte, value uint32) []byte {
+	return binary.LittleEndian.AppendUint32([]byte{cs}, value)
+}
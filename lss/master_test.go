@@ -0,0 +1,242 @@
+package lss
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func busPair() (*can.Bus, *can.Bus) {
+	a, b := net.Pipe()
+	busA := can.NewBus(can.NewReadWriteCloser(a), "a")
+	busB := can.NewBus(can.NewReadWriteCloser(b), "b")
+	go busA.ConnectAndPublish()
+	go busB.ConnectAndPublish()
+
+	return busA, busB
+}
+
+// testSlave answers the LSS requests of the master
+type testSlave struct {
+	identity Identity
+	bus      *can.Bus
+
+	lock     sync.Mutex
+	mode     Mode
+	nodeID   uint8
+	timing   uint8
+	position byte
+	selected int
+}
+
+func (slave *testSlave) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frame.CobID != MasterCobID {
+		return
+	}
+
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	data := frame.Data
+	value := binary.LittleEndian.Uint32(data[1:5])
+	switch cs := data[0]; cs {
+	case csSwitchStateGlobal:
+		slave.mode = Mode(data[1])
+	case csSwitchStateVendor, csSwitchStateProduct, csSwitchStateRevision, csSwitchStateSerial:
+		index := int(cs - csSwitchStateVendor)
+		if index == slave.selected && value == slave.identity.Field(uint8(index)) {
+			slave.selected++
+		} else {
+			slave.selected = 0
+		}
+
+		if slave.selected == 4 {
+			slave.selected = 0
+			slave.mode = ModeConfiguration
+			slave.respond(csSwitchStateSelective)
+		}
+	case csFastscan:
+		if slave.mode != ModeWaiting || slave.nodeID != UnconfiguredNodeID {
+			return
+		}
+
+		if data[5] == fastscanReset {
+			slave.position = 0
+			slave.respond(csIdentifySlave)
+			return
+		}
+
+		mask := uint32(0xFFFFFFFF) << data[5]
+		if data[6] != slave.position || (value^slave.identity.Field(data[6]))&mask != 0 {
+			return
+		}
+
+		slave.respond(csIdentifySlave)
+		if data[5] == 0 {
+			if data[7] < slave.position {
+				slave.mode = ModeConfiguration
+			}
+			slave.position = data[7]
+		}
+	case csConfigureNodeID:
+		if slave.mode == ModeConfiguration {
+			if data[1] > canopen.MaxNodeID && data[1] != UnconfiguredNodeID {
+				slave.respond(cs, errorCodeNotSupported)
+				return
+			}
+			slave.nodeID = data[1]
+			slave.respond(cs, errorCodeSuccess)
+		}
+	case csConfigureBitTiming:
+		if slave.mode == ModeConfiguration {
+			slave.timing = data[2]
+			slave.respond(cs, errorCodeSuccess)
+		}
+	case csStoreConfiguration:
+		if slave.mode == ModeConfiguration {
+			slave.respond(cs, errorCodeImplementation, 7)
+		}
+	case csInquireVendor, csInquireProduct, csInquireRevision, csInquireSerial:
+		if slave.mode == ModeConfiguration {
+			slave.respond(withValue(cs, slave.identity.Field(cs-csInquireVendor))...)
+		}
+	case csInquireNodeID:
+		if slave.mode == ModeConfiguration {
+			slave.respond(cs, slave.nodeID)
+		}
+	}
+}
+
+func (slave *testSlave) respond(data ...byte) {
+	frame := canopen.NewFrame(SlaveCobID, append(data, make([]byte, 8-len(data))...))
+	_ = slave.bus.PublishMinDuration(frame.CANFrame(), 0)
+}
+
+func newTestSlaves(bus *can.Bus, identities ...Identity) []*testSlave {
+	var slaves []*testSlave
+	for _, identity := range identities {
+		slave := &testSlave{identity: identity, bus: bus, nodeID: UnconfiguredNodeID}
+		bus.SubscribeFunc(slave.handle)
+		slaves = append(slaves, slave)
+	}
+
+	return slaves
+}
+
+func TestMaster(t *testing.T) {
+	masterBus, slaveBus := busPair()
+	defer masterBus.Disconnect()
+
+	identity := Identity{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xCAFE}
+	slaves := newTestSlaves(slaveBus, identity, Identity{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xBEEF})
+
+	master := NewMaster(masterBus)
+	master.Timeout = 50 * time.Millisecond
+
+	if err := master.ConfigureNodeID(3); !errors.Is(err, ErrTimeout) {
+		t.Log("Expected timeout without slave in configuration mode", err)
+		t.FailNow()
+	}
+
+	if err := master.SwitchStateSelective(identity); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := master.ConfigureNodeID(3); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := master.ConfigureBitTiming(BitTiming250K); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var configurationError *ConfigurationError
+	if err := master.StoreConfiguration(); !errors.As(err, &configurationError) || configurationError.SpecificCode != 7 {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	if inquired, err := master.InquireIdentity(); err != nil || inquired != identity {
+		t.Log("Unexpected identity", inquired, err)
+		t.FailNow()
+	}
+
+	if nodeID, err := master.InquireNodeID(); err != nil || nodeID != 3 {
+		t.Log("Unexpected node id", nodeID, err)
+		t.FailNow()
+	}
+
+	if err := master.SwitchStateGlobal(ModeWaiting); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Wait until the slave processed the request
+	time.Sleep(10 * time.Millisecond)
+
+	slaves[0].lock.Lock()
+	defer slaves[0].lock.Unlock()
+	if slaves[0].mode != ModeWaiting || slaves[0].nodeID != 3 || slaves[0].timing != uint8(BitTiming250K) || slaves[1].nodeID != UnconfiguredNodeID {
+		t.Log("Unexpected slave configuration", slaves[0].mode, slaves[0].nodeID, slaves[0].timing)
+		t.FailNow()
+	}
+}
+
+func TestFastscan(t *testing.T) {
+	masterBus, slaveBus := busPair()
+	defer masterBus.Disconnect()
+
+	identities := map[Identity]bool{
+		{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xCAFE}: true,
+		{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xBEEF}: true,
+	}
+	var list []Identity
+	for identity := range identities {
+		list = append(list, identity)
+	}
+	newTestSlaves(slaveBus, list...)
+
+	master := NewMaster(masterBus)
+	master.Timeout = 10 * time.Millisecond
+
+	// Assign node ids until all slaves are configured
+	for nodeID := uint8(1); ; nodeID++ {
+		identity, err := master.Fastscan()
+		if errors.Is(err, ErrNoSlave) {
+			break
+		} else if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if !identities[identity] {
+			t.Log("Unexpected identity", identity)
+			t.FailNow()
+		}
+		delete(identities, identity)
+
+		if err := master.ConfigureNodeID(nodeID); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if err := master.SwitchStateGlobal(ModeWaiting); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	if len(identities) != 0 {
+		t.Log("Slaves were not found", identities)
+		t.FailNow()
+	}
+}
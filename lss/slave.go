@@ -0,0 +1,316 @@
+package lss
+
+import (
+	"encoding/binary"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo/sdoServer"
+	"sync"
+	"time"
+)
+
+// Configuration is the configuration of a slave, it is persisted with the store configuration service.
+type Configuration struct {
+	NodeID    uint8
+	BitTiming BitTiming
+}
+
+// Slave answers the LSS requests of the master for a local node.
+// A configured node id becomes active on Activate (NMT reset communication),
+// an unconfigured node activates it when the master switches it back to waiting mode.
+type Slave struct {
+	// NodeID is the node id on start, unconfigured nodes (UnconfiguredNodeID) take part in Fastscan.
+	// The slave doesn't change it, Active returns the node id while the slave is running.
+	NodeID uint8
+	// BitTiming is the bit timing on start, Active returns the bit timing while the slave is running
+	BitTiming BitTiming
+	// ObjectDictionary contains the identity object (0x1018)
+	ObjectDictionary *od.ObjectDictionary
+	// Server receives the node id when it becomes active (optional)
+	Server *sdoServer.Server
+
+	// OnNodeID is called when a new node id becomes active (optional)
+	OnNodeID func(nodeID uint8)
+	// Store persists the configuration, the store configuration service is not supported if nil
+	Store func(configuration Configuration) error
+	// OnActivateBitTiming switches to the bit timing after the delay and waits for the delay again before sending,
+	// configuring the bit timing is not supported if nil
+	OnActivateBitTiming func(timing BitTiming, delay time.Duration)
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock     sync.Mutex
+	running  bool
+	active   Configuration
+	identity Identity
+	mode     Mode
+	pending  Configuration
+	selected int
+	position byte
+}
+
+// Start reads the identity from the object dictionary and starts answering the LSS requests.
+func (slave *Slave) Start(bus *can.Bus) error {
+	identity, err := ReadIdentity(slave.ObjectDictionary)
+	if err != nil {
+		return err
+	}
+
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	if slave.running {
+		return nil
+	}
+
+	slave.active = Configuration{NodeID: slave.NodeID, BitTiming: slave.BitTiming}
+	if slave.active.NodeID == 0 || slave.active.NodeID > canopen.MaxNodeID {
+		slave.active.NodeID = UnconfiguredNodeID
+	}
+
+	slave.bus = bus
+	slave.running = true
+	slave.identity = identity
+	slave.mode = ModeWaiting
+	slave.pending = slave.active
+
+	slave.handler = can.NewHandler(slave.handle)
+	bus.Subscribe(slave.handler)
+
+	return nil
+}
+
+// Stop stops answering the LSS requests.
+func (slave *Slave) Stop() {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	if !slave.running {
+		return
+	}
+
+	slave.bus.Unsubscribe(slave.handler)
+	slave.running = false
+}
+
+// Mode returns the LSS mode of the slave.
+func (slave *Slave) Mode() Mode {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	return slave.mode
+}
+
+// Active returns the active node id and bit timing.
+func (slave *Slave) Active() Configuration {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	return slave.active
+}
+
+// Configuration returns the pending configuration, which becomes active on Activate.
+func (slave *Slave) Configuration() Configuration {
+	slave.lock.Lock()
+	defer slave.lock.Unlock()
+
+	return slave.pending
+}
+
+// Activate makes the pending node id active, it should be called on NMT reset communication.
+func (slave *Slave) Activate() {
+	slave.lock.Lock()
+	nodeID, changed := slave.activate()
+	slave.lock.Unlock()
+
+	if changed {
+		slave.notify(nodeID)
+	}
+}
+
+// activate makes the pending node id active, the lock must be held
+func (slave *Slave) activate() (uint8, bool) {
+	if slave.pending.NodeID == slave.active.NodeID {
+		return slave.active.NodeID, false
+	}
+
+	slave.active.NodeID = slave.pending.NodeID
+	return slave.active.NodeID, true
+}
+
+func (slave *Slave) notify(nodeID uint8) {
+	if slave.Server != nil {
+		slave.Server.SetNodeID(nodeID)
+	}
+
+	if slave.OnNodeID != nil {
+		slave.OnNodeID(nodeID)
+	}
+}
+
+func (slave *Slave) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.CobID != MasterCobID || frm.Length != 8 {
+		return
+	}
+
+	slave.lock.Lock()
+	if !slave.running {
+		slave.lock.Unlock()
+		return
+	}
+
+	data := frame.Data
+	cs := data[0]
+	switch cs {
+	case csSwitchStateGlobal:
+		slave.switchState(Mode(data[1]))
+	case csSwitchStateVendor, csSwitchStateProduct, csSwitchStateRevision, csSwitchStateSerial:
+		slave.switchStateSelective(cs, binary.LittleEndian.Uint32(data[1:5]))
+	case csFastscan:
+		slave.fastscan(binary.LittleEndian.Uint32(data[1:5]), data[5], data[6], data[7])
+	case csStoreConfiguration:
+		if slave.mode == ModeConfiguration {
+			// The configuration is stored without the lock, so the hook may use the slave
+			configuration := slave.pending
+			slave.lock.Unlock()
+
+			slave.store(configuration)
+			return
+		}
+	case csActivateBitTiming:
+		if slave.mode == ModeConfiguration && slave.OnActivateBitTiming != nil {
+			slave.active.BitTiming = slave.pending.BitTiming
+			timing := slave.active.BitTiming
+			slave.lock.Unlock()
+
+			slave.OnActivateBitTiming(timing, time.Duration(binary.LittleEndian.Uint16(data[1:3]))*time.Millisecond)
+			return
+		}
+	default:
+		if slave.mode == ModeConfiguration {
+			slave.configure(cs, data)
+		}
+	}
+
+	nodeID := slave.active.NodeID
+	activated := false
+	if cs == csSwitchStateGlobal && slave.mode == ModeWaiting && slave.active.NodeID == UnconfiguredNodeID && slave.pending.NodeID != UnconfiguredNodeID {
+		nodeID, activated = slave.activate()
+	}
+	slave.lock.Unlock()
+
+	if activated {
+		slave.notify(nodeID)
+	}
+}
+
+// switchState changes the mode, the lock must be held
+func (slave *Slave) switchState(mode Mode) {
+	if mode != ModeWaiting && mode != ModeConfiguration {
+		return
+	}
+
+	slave.mode = mode
+	slave.selected = 0
+}
+
+// switchStateSelective compares the identity sent by the master, the lock must be held
+func (slave *Slave) switchStateSelective(cs byte, value uint32) {
+	if slave.mode != ModeWaiting {
+		return
+	}
+
+	field := int(cs - csSwitchStateVendor)
+	if field != slave.selected || value != slave.identity.Field(uint8(field)) {
+		slave.selected = 0
+		return
+	}
+
+	slave.selected++
+	if slave.selected == int(fastscanSubIndexes) {
+		slave.selected = 0
+		slave.mode = ModeConfiguration
+		slave.respond(csSwitchStateSelective)
+	}
+}
+
+// fastscan compares the bits of the identity sent by the master, the lock must be held
+func (slave *Slave) fastscan(value uint32, bitChecked byte, subIndex byte, next byte) {
+	if slave.mode != ModeWaiting || slave.active.NodeID != UnconfiguredNodeID {
+		return
+	}
+
+	if bitChecked == fastscanReset {
+		slave.position = 0
+		slave.respond(csIdentifySlave)
+		return
+	}
+
+	if bitChecked >= fastscanBits || subIndex != slave.position || next >= fastscanSubIndexes {
+		return
+	}
+
+	if (value^slave.identity.Field(subIndex))&(0xFFFFFFFF<<bitChecked) != 0 {
+		return
+	}
+
+	slave.respond(csIdentifySlave)
+	if bitChecked == 0 {
+		// The complete identity matched after the last sub index
+		if next < slave.position {
+			slave.mode = ModeConfiguration
+		}
+		slave.position = next
+	}
+}
+
+// configure answers the services of the configuration mode, the lock must be held
+func (slave *Slave) configure(cs byte, data []byte) {
+	switch cs {
+	case csConfigureNodeID:
+		nodeID := data[1]
+		if (nodeID == 0 || nodeID > canopen.MaxNodeID) && nodeID != UnconfiguredNodeID {
+			slave.respond(cs, errorCodeNotSupported)
+			return
+		}
+
+		slave.pending.NodeID = nodeID
+		slave.respond(cs, errorCodeSuccess)
+	case csConfigureBitTiming:
+		timing := BitTiming(data[2])
+		if _, ok := bitRates[timing]; slave.OnActivateBitTiming == nil || data[1] != bitTimingTableSelector || !ok && timing != BitTimingAuto {
+			slave.respond(cs, errorCodeNotSupported)
+			return
+		}
+
+		slave.pending.BitTiming = timing
+		slave.respond(cs, errorCodeSuccess)
+	case csInquireVendor, csInquireProduct, csInquireRevision, csInquireSerial:
+		slave.respond(withValue(cs, slave.identity.Field(cs-csInquireVendor))...)
+	case csInquireNodeID:
+		slave.respond(cs, slave.active.NodeID)
+	}
+}
+
+// store persists the configuration and responds with the result
+func (slave *Slave) store(configuration Configuration) {
+	if slave.Store == nil {
+		slave.respond(csStoreConfiguration, errorCodeNotSupported)
+		return
+	}
+
+	if err := slave.Store(configuration); err != nil {
+		slave.respond(csStoreConfiguration, errorCodeStorage)
+		return
+	}
+
+	slave.respond(csStoreConfiguration, errorCodeSuccess)
+}
+
+func (slave *Slave) respond(data ...byte) {
+	frame := canopen.NewFrame(SlaveCobID, append(data, make([]byte, 8-len(data))...))
+	_ = slave.bus.PublishMinDuration(frame.CANFrame(), 0)
+}
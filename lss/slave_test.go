@@ -0,0 +1,188 @@
+package lss
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"github.com/FabianPetersen/canopen/sdo/sdoClient"
+	"github.com/FabianPetersen/canopen/sdo/sdoServer"
+	"net"
+	"testing"
+	"time"
+)
+
+func getObjectDictionary(identity Identity) *od.ObjectDictionary {
+	dictionary := od.NewObjectDictionary()
+	object := od.NewRecord(IdentityIndex, "Identity object")
+	object.AddSubIndex(&od.Variable{SubIndex: 0, DataType: sdo.DATA_TYPE_UNSIGNED_8, AccessType: od.ACCESS_TYPE_CONST, DefaultValue: []byte{4}})
+	for subIndex := uint8(1); subIndex <= 4; subIndex++ {
+		object.AddSubIndex(&od.Variable{
+			SubIndex:     subIndex,
+			DataType:     sdo.DATA_TYPE_UNSIGNED_32,
+			AccessType:   od.ACCESS_TYPE_RO,
+			DefaultValue: binary.LittleEndian.AppendUint32(nil, identity.Field(subIndex-1)),
+		})
+	}
+	dictionary.Add(object)
+
+	return dictionary
+}
+
+func TestSlave(t *testing.T) {
+	a, b := net.Pipe()
+	masterBus := can.NewBus(can.NewReadWriteCloser(a), "master")
+	slaveBus := can.NewBus(can.NewReadWriteCloser(b), "slave")
+	go masterBus.ConnectAndPublish()
+	defer masterBus.Disconnect()
+
+	identities := []Identity{
+		{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xCAFE},
+		{VendorID: 0x12, ProductCode: 0x3456, RevisionNumber: 0x10002, SerialNumber: 0xBEEF},
+	}
+
+	stored := make(chan Configuration, 1)
+	timings := make(chan BitTiming, 1)
+	dictionary := getObjectDictionary(identities[0])
+	server := &sdoServer.Server{NodeId: UnconfiguredNodeID, ObjectDictionary: dictionary}
+	slave := &Slave{
+		ObjectDictionary: dictionary,
+		Server:           server,
+		Store: func(configuration Configuration) error {
+			stored <- configuration
+			return nil
+		},
+		OnActivateBitTiming: func(timing BitTiming, delay time.Duration) {
+			timings <- timing
+		},
+	}
+	if err := slave.Start(slaveBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer slave.Stop()
+
+	// The second slave doesn't support storing the configuration
+	other := &Slave{ObjectDictionary: getObjectDictionary(identities[1])}
+	if err := other.Start(slaveBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer other.Stop()
+
+	go server.Listen(slaveBus)
+	defer slaveBus.Disconnect()
+	time.Sleep(10 * time.Millisecond)
+
+	master := NewMaster(masterBus)
+	master.Timeout = 10 * time.Millisecond
+
+	found := map[Identity]uint8{}
+	for nodeID := uint8(1); ; nodeID++ {
+		identity, err := master.Fastscan()
+		if errors.Is(err, ErrNoSlave) {
+			break
+		} else if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+		found[identity] = nodeID
+
+		if err := master.ConfigureNodeID(nodeID); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		err = master.StoreConfiguration()
+		var configurationError *ConfigurationError
+		if identity == identities[0] && err != nil || identity == identities[1] && (!errors.As(err, &configurationError) || configurationError.Code != errorCodeNotSupported) {
+			t.Log("Unexpected store result", identity, err)
+			t.FailNow()
+		}
+
+		if err := master.SwitchStateGlobal(ModeWaiting); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	if len(found) != 2 || found[identities[0]] == 0 || found[identities[1]] == 0 {
+		t.Log("Unexpected slaves", found)
+		t.FailNow()
+	}
+
+	nodeID := found[identities[0]]
+	if configuration := <-stored; configuration.NodeID != nodeID {
+		t.Log("Unexpected stored configuration", configuration)
+		t.FailNow()
+	}
+
+	// Switching to waiting mode activated the node id of the unconfigured nodes
+	time.Sleep(10 * time.Millisecond)
+	if slave.Active().NodeID != nodeID || server.NodeID() != nodeID || other.Active().NodeID != found[identities[1]] {
+		t.Log("Node id not active", slave.Active(), server.NodeID(), other.Active())
+		t.FailNow()
+	}
+
+	// The SDO server answers with the new node id
+	upload := sdoClient.Upload{
+		ObjectIndex:   canopen.NewObjectIndex(IdentityIndex, 4),
+		RequestCobID:  canopen.MessageTypeRSDO + uint16(nodeID),
+		ResponseCobID: canopen.MessageTypeTSDO + uint16(nodeID),
+	}
+	if data, err := upload.Do(masterBus); err != nil || !bytes.Equal(data, []byte{0xFE, 0xCA, 0x00, 0x00}) {
+		t.Log("Unexpected serial number", data, err)
+		t.FailNow()
+	}
+
+	// Configured nodes are selected by their identity
+	if err := master.SwitchStateSelective(identities[0]); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if inquired, err := master.InquireIdentity(); err != nil || inquired != identities[0] {
+		t.Log("Unexpected identity", inquired, err)
+		t.FailNow()
+	}
+
+	if err := master.ConfigureBitTiming(BitTiming(5)); err == nil {
+		t.Log("Reserved bit timing was accepted")
+		t.FailNow()
+	}
+
+	if err := master.ConfigureBitTiming(BitTiming500K); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := master.ActivateBitTiming(time.Millisecond); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if timing := <-timings; timing != BitTiming500K || slave.Active().BitTiming != BitTiming500K {
+		t.Log("Unexpected bit timing", timing, slave.Active())
+		t.FailNow()
+	}
+
+	// A new node id of a configured node becomes active after reset communication
+	if err := master.ConfigureNodeID(10); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if nodeID, err := master.InquireNodeID(); err != nil || nodeID != found[identities[0]] {
+		t.Log("Unexpected active node id", nodeID, err)
+		t.FailNow()
+	}
+
+	slave.Activate()
+	if server.NodeID() != 10 {
+		t.Log("Node id not activated", server.NodeID())
+		t.FailNow()
+	}
+}
@@ -1,27 +1,188 @@
 package mpdo
 
 import (
+	"context"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
 )
 
+// DefaultBufferSize is the number of buffered samples if the consumer has no buffer size
+const DefaultBufferSize = 16
+
+// Sample is the data of a received MPDO.
+type Sample struct {
+	// CobID is the COB-ID the MPDO was received with
+	CobID uint16
+	Data  [4]byte
+	// Time is the time the MPDO was received
+	Time time.Time
+}
+
+// Consumer receives the MPDOs of an object.
 type Consumer struct {
 	ObjectIndex canopen.ObjectIndex
 
 	ObserveCobID uint16
 	ReceiveCobID uint8
+	// Mode is the address mode, with SourceAddressMode ReceiveCobID is the node id of the producer.
+	// If bit 7 of ReceiveCobID is set, the MPDOs are always received in the destination address mode.
+	Mode AddressMode
+	// BufferSize is the number of buffered samples, 0 uses DefaultBufferSize
+	BufferSize int
+}
+
+// Subscription receives the samples of a consumer.
+// Samples are dropped if the buffer is full, so a slow reader never blocks the bus.
+type Subscription struct {
+	// C receives the samples, it is closed when the subscription ends
+	C <-chan Sample
+
+	consumer Consumer
+	samples  chan Sample
+	bus      *can.Bus
+	handler  can.Handler
+	done     chan struct{}
+
+	lock     sync.Mutex
+	closed   bool
+	received uint64
+	dropped  uint64
+	invalid  uint64
+}
+
+// Subscribe returns a subscription which receives the samples until ctx is done or it is closed.
+func (consumer Consumer) Subscribe(ctx context.Context, bus *can.Bus) *Subscription {
+	size := consumer.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+
+	samples := make(chan Sample, size)
+	sub := &Subscription{
+		C:        samples,
+		consumer: consumer,
+		samples:  samples,
+		bus:      bus,
+		done:     make(chan struct{}),
+	}
+
+	sub.handler = can.NewHandler(sub.handle)
+	bus.Subscribe(sub.handler)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			sub.Close()
+		case <-sub.done:
+		}
+	}()
+
+	return sub
 }
 
+// Listen sends the data of the received MPDOs to the channel.
+//
+// Deprecated: Listen can't be stopped, use Subscribe instead.
 func (consumer *Consumer) Listen(bus *can.Bus, channel chan [4]byte) {
-	bus.SubscribeFunc(func(frame can.Frame) {
-		// Check if the objectIndex is a match
-		if frame.ID == uint32(consumer.ObserveCobID) && frame.Data[0] == consumer.ReceiveCobID && frame.Data[1] == consumer.ObjectIndex.Index.B0 && frame.Data[2] == consumer.ObjectIndex.Index.B1 && frame.Data[3] == consumer.ObjectIndex.SubIndex {
-			channel <- [4]byte{
-				frame.Data[4],
-				frame.Data[5],
-				frame.Data[6],
-				frame.Data[7],
-			}
+	sub := consumer.Subscribe(context.Background(), bus)
+	go func() {
+		for sample := range sub.C {
+			channel <- sample.Data
 		}
-	})
+	}()
+}
+
+// Close removes the subscription from the bus and closes C.
+func (sub *Subscription) Close() {
+	sub.lock.Lock()
+	if sub.closed {
+		sub.lock.Unlock()
+		return
+	}
+	sub.closed = true
+	close(sub.samples)
+	close(sub.done)
+	sub.lock.Unlock()
+
+	sub.bus.Unsubscribe(sub.handler)
+}
+
+// Done returns a channel which is closed when the subscription ends.
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Received returns the number of samples which were put into the buffer.
+func (sub *Subscription) Received() uint64 {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	return sub.received
+}
+
+// Dropped returns the number of samples which were dropped because the buffer was full.
+func (sub *Subscription) Dropped() uint64 {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	return sub.dropped
+}
+
+// Invalid returns the number of frames with the observed COB-ID which were ignored because of an invalid length.
+func (sub *Subscription) Invalid() uint64 {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	return sub.invalid
+}
+
+func (sub *Subscription) handle(frm can.Frame) {
+	cobID := uint16(frm.ID & canopen.MaskIDSff)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || cobID != sub.consumer.ObserveCobID {
+		return
+	}
+
+	now := time.Now()
+
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	if sub.closed {
+		return
+	}
+
+	if frm.Length != 8 {
+		sub.invalid++
+		return
+	}
+
+	message, _ := Decode(canopen.NewFrame(cobID, frm.Data[:frm.Length]))
+	if !sub.consumer.accepts(message) {
+		return
+	}
+
+	select {
+	case sub.samples <- Sample{CobID: cobID, Data: message.Data, Time: now}:
+		sub.received++
+	default:
+		sub.dropped++
+	}
+}
+
+func (consumer Consumer) accepts(message Message) bool {
+	// Bit 7 of the address byte selects the destination address mode, as before the Mode was added
+	mode := consumer.Mode
+	if consumer.ReceiveCobID&canopen.MPDO != 0 {
+		mode = DestinationAddressMode
+	}
+
+	if message.Mode != mode || !message.ObjectIndex.Compare(consumer.ObjectIndex) {
+		return false
+	}
+
+	// DAM MPDOs to node 0 are received by all consumers
+	nodeID := consumer.ReceiveCobID & canopen.MaskNodeID
+	return message.NodeID == nodeID || mode == DestinationAddressMode && message.NodeID == 0
 }
@@ -0,0 +1,171 @@
+package mpdo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/emcy"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"sync"
+)
+
+// ErrObjectSize is returned if an object doesn't fit into the 4 data bytes of an MPDO
+var ErrObjectSize = errors.New("object does not fit into an MPDO")
+
+// Dispatcher writes received MPDOs to the local object dictionary.
+// DAM MPDOs addressed to the node (or to all nodes) are written to the contained object,
+// SAM MPDOs are mapped to local objects by the object dispatcher list.
+type Dispatcher struct {
+	NodeID uint8
+	// CobID is the COB-ID of the MPDOs (the COB-ID of the RPDO)
+	CobID            uint16
+	ObjectDictionary *od.ObjectDictionary
+	// DispatcherList maps SAM MPDOs, it is read from the object dictionary (0x1FD0-0x1FFF) on Start if nil
+	DispatcherList []DispatcherEntry
+	// Emergency reports DAM MPDOs to unavailable objects (optional)
+	Emergency *emcy.Producer
+
+	// OnReceive is called after a value was written to the local object (optional)
+	OnReceive func(message Message, objectIndex canopen.ObjectIndex)
+	// OnError is called if a received MPDO can't be processed (optional)
+	OnError func(message Message, err error)
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock    sync.Mutex
+	running bool
+}
+
+// Start reads the dispatcher list if needed and starts processing the MPDOs of the bus.
+func (dispatcher *Dispatcher) Start(bus *can.Bus) error {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	if dispatcher.running {
+		return nil
+	}
+
+	if dispatcher.DispatcherList == nil {
+		list, err := ReadDispatcherList(dispatcher.ObjectDictionary)
+		if err != nil {
+			return err
+		}
+		dispatcher.DispatcherList = list
+	}
+
+	dispatcher.bus = bus
+	dispatcher.running = true
+	dispatcher.handler = can.NewHandler(dispatcher.handle)
+	bus.Subscribe(dispatcher.handler)
+
+	return nil
+}
+
+// Stop stops processing MPDOs.
+func (dispatcher *Dispatcher) Stop() {
+	dispatcher.lock.Lock()
+	defer dispatcher.lock.Unlock()
+
+	if !dispatcher.running {
+		return
+	}
+
+	dispatcher.bus.Unsubscribe(dispatcher.handler)
+	dispatcher.running = false
+}
+
+// Dispatch writes a message to the local object dictionary.
+// Messages which are not addressed to the node are ignored and return no error.
+func (dispatcher *Dispatcher) Dispatch(message Message) error {
+	var objectIndex canopen.ObjectIndex
+	switch message.Mode {
+	case DestinationAddressMode:
+		if message.NodeID != 0 && message.NodeID != dispatcher.NodeID {
+			return nil
+		}
+
+		objectIndex = message.ObjectIndex
+		if err := write(dispatcher.ObjectDictionary, objectIndex, message.Data, dispatcher.ObjectDictionary.Write); err != nil {
+			if dispatcher.Emergency != nil {
+				dispatcher.Emergency.Error(emcy.ErrorDAMMPDO, emcy.RegisterCommunication, [5]byte{})
+			}
+			return err
+		}
+	case SourceAddressMode:
+		var ok bool
+		if objectIndex, ok = dispatcher.lookup(message); !ok {
+			return nil
+		}
+
+		// The dispatcher list defines the local objects, so they are written like mapped RPDO objects
+		if err := write(dispatcher.ObjectDictionary, objectIndex, message.Data, dispatcher.ObjectDictionary.SetValue); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid address mode %d", message.Mode)
+	}
+
+	if dispatcher.OnReceive != nil {
+		dispatcher.OnReceive(message, objectIndex)
+	}
+
+	return nil
+}
+
+// lookup returns the local object of a SAM MPDO
+func (dispatcher *Dispatcher) lookup(message Message) (canopen.ObjectIndex, bool) {
+	for _, entry := range dispatcher.DispatcherList {
+		if objectIndex, ok := entry.Map(message); ok {
+			return objectIndex, true
+		}
+	}
+
+	return canopen.ObjectIndex{}, false
+}
+
+func (dispatcher *Dispatcher) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.CobID != dispatcher.CobID {
+		return
+	}
+
+	dispatcher.lock.Lock()
+	running := dispatcher.running
+	dispatcher.lock.Unlock()
+	if !running {
+		return
+	}
+
+	message, err := Decode(canopen.NewFrame(frame.CobID, frm.Data[:frm.Length]))
+	if err == nil {
+		err = dispatcher.Dispatch(message)
+	}
+
+	if err != nil && dispatcher.OnError != nil {
+		dispatcher.OnError(message, err)
+	}
+}
+
+// write stores the data bytes of the object's size with the write function
+func write(dictionary *od.ObjectDictionary, objectIndex canopen.ObjectIndex, data [4]byte, fn func(canopen.ObjectIndex, []byte) canopen.SDOAbortCode) error {
+	variable, abortCode := dictionary.Variable(objectIndex)
+	if abortCode != canopen.NO_ERROR {
+		return fmt.Errorf("writing %s: %s", objectIndex.String(), canopen.GetAbortCodeText(abortCode))
+	}
+
+	size := sdo.DataTypeSize(variable.DataType)
+	if size > len(data) {
+		return fmt.Errorf("writing %s: %w", objectIndex.String(), ErrObjectSize)
+	} else if size <= 0 {
+		size = len(data)
+	}
+
+	if abortCode = fn(objectIndex, data[:size]); abortCode != canopen.NO_ERROR {
+		return fmt.Errorf("writing %s: %s", objectIndex.String(), canopen.GetAbortCodeText(abortCode))
+	}
+
+	return nil
+}
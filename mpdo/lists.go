@@ -0,0 +1,142 @@
+package mpdo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+)
+
+const (
+	// ScannerListIndex is the index of the first object scanner list, the lists are at 0x1FA0-0x1FCF
+	ScannerListIndex uint16 = 0x1FA0
+	// ScannerListLastIndex is the index of the last object scanner list
+	ScannerListLastIndex uint16 = 0x1FCF
+	// DispatcherListIndex is the index of the first object dispatcher list, the lists are at 0x1FD0-0x1FFF
+	DispatcherListIndex uint16 = 0x1FD0
+	// DispatcherListLastIndex is the index of the last object dispatcher list
+	DispatcherListLastIndex uint16 = 0x1FFF
+)
+
+// ScannerEntry is an entry of the object scanner list of a SAM producer,
+// it allows BlockSize consecutive sub indexes starting at SubIndex to be sent.
+type ScannerEntry struct {
+	Index     uint16
+	SubIndex  uint8
+	BlockSize uint8
+}
+
+// ParseScannerEntry decodes a scanner list entry (bits 24-31 block size, 8-23 index, 0-7 sub index).
+func ParseScannerEntry(entry uint32) ScannerEntry {
+	return ScannerEntry{
+		Index:     uint16(entry >> 8),
+		SubIndex:  uint8(entry),
+		BlockSize: uint8(entry >> 24),
+	}
+}
+
+// Entry returns the encoded scanner list entry.
+func (entry ScannerEntry) Entry() uint32 {
+	return uint32(entry.BlockSize)<<24 | uint32(entry.Index)<<8 | uint32(entry.SubIndex)
+}
+
+// Contains returns true if the object index is part of the entry.
+func (entry ScannerEntry) Contains(objectIndex canopen.ObjectIndex) bool {
+	return objectIndex.Index.Index() == entry.Index && contains(entry.SubIndex, entry.BlockSize, objectIndex.SubIndex)
+}
+
+// DispatcherEntry is an entry of the object dispatcher list of a SAM consumer.
+// It maps BlockSize consecutive sub indexes of the sender's object to the local object.
+type DispatcherEntry struct {
+	SenderNodeID   uint8
+	SenderIndex    uint16
+	SenderSubIndex uint8
+	LocalIndex     uint16
+	LocalSubIndex  uint8
+	BlockSize      uint8
+}
+
+// ParseDispatcherEntry decodes a dispatcher list entry (bits 56-63 block size, 40-55 local index, 32-39 local sub index,
+// 16-31 sender index, 8-15 sender sub index, 0-7 sender node id).
+func ParseDispatcherEntry(entry uint64) DispatcherEntry {
+	return DispatcherEntry{
+		SenderNodeID:   uint8(entry),
+		SenderSubIndex: uint8(entry >> 8),
+		SenderIndex:    uint16(entry >> 16),
+		LocalSubIndex:  uint8(entry >> 32),
+		LocalIndex:     uint16(entry >> 40),
+		BlockSize:      uint8(entry >> 56),
+	}
+}
+
+// Entry returns the encoded dispatcher list entry.
+func (entry DispatcherEntry) Entry() uint64 {
+	return uint64(entry.BlockSize)<<56 | uint64(entry.LocalIndex)<<40 | uint64(entry.LocalSubIndex)<<32 |
+		uint64(entry.SenderIndex)<<16 | uint64(entry.SenderSubIndex)<<8 | uint64(entry.SenderNodeID)
+}
+
+// Map returns the local object of a SAM MPDO or false if the entry doesn't match.
+func (entry DispatcherEntry) Map(message Message) (canopen.ObjectIndex, bool) {
+	if message.Mode != SourceAddressMode || message.NodeID != entry.SenderNodeID || message.ObjectIndex.Index.Index() != entry.SenderIndex {
+		return canopen.ObjectIndex{}, false
+	}
+
+	if !contains(entry.SenderSubIndex, entry.BlockSize, message.ObjectIndex.SubIndex) {
+		return canopen.ObjectIndex{}, false
+	}
+
+	return canopen.NewObjectIndex(entry.LocalIndex, entry.LocalSubIndex+(message.ObjectIndex.SubIndex-entry.SenderSubIndex)), true
+}
+
+func contains(first uint8, blockSize uint8, subIndex uint8) bool {
+	return subIndex >= first && int(subIndex) < int(first)+int(blockSize)
+}
+
+// ReadScannerList reads the entries of the object scanner lists from an object dictionary.
+func ReadScannerList(dictionary *od.ObjectDictionary) ([]ScannerEntry, error) {
+	var entries []ScannerEntry
+	err := readLists(dictionary, ScannerListIndex, ScannerListLastIndex, 4, func(entry uint64) {
+		entries = append(entries, ParseScannerEntry(uint32(entry)))
+	})
+
+	return entries, err
+}
+
+// ReadDispatcherList reads the entries of the object dispatcher lists from an object dictionary.
+func ReadDispatcherList(dictionary *od.ObjectDictionary) ([]DispatcherEntry, error) {
+	var entries []DispatcherEntry
+	err := readLists(dictionary, DispatcherListIndex, DispatcherListLastIndex, 8, func(entry uint64) {
+		entries = append(entries, ParseDispatcherEntry(entry))
+	})
+
+	return entries, err
+}
+
+// readLists calls add for every entry of the existing list objects, empty entries (0) are skipped
+func readLists(dictionary *od.ObjectDictionary, first uint16, last uint16, size int, add func(uint64)) error {
+	for index := first; index <= last; index++ {
+		object := dictionary.Object(index)
+		if object == nil {
+			continue
+		}
+
+		for _, variable := range object.SubIndexes() {
+			if variable.SubIndex == 0 {
+				continue
+			}
+
+			data, abortCode := dictionary.Value(canopen.NewObjectIndex(index, variable.SubIndex))
+			if abortCode != canopen.NO_ERROR || len(data) != size {
+				return fmt.Errorf("invalid entry %X:%d", index, variable.SubIndex)
+			}
+
+			buffer := make([]byte, 8)
+			copy(buffer, data)
+			if entry := binary.LittleEndian.Uint64(buffer); entry != 0 {
+				add(entry)
+			}
+		}
+	}
+
+	return nil
+}
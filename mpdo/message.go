@@ -0,0 +1,77 @@
+package mpdo
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+)
+
+// AddressMode is the address type of an MPDO (bit 7 of byte 0)
+type AddressMode uint8
+
+const (
+	// SourceAddressMode (SAM) MPDOs contain the node id of the producer and an object of the producer
+	SourceAddressMode AddressMode = iota
+	// DestinationAddressMode (DAM) MPDOs contain the node id of the consumer and an object of the consumer
+	DestinationAddressMode
+)
+
+func (mode AddressMode) String() string {
+	switch mode {
+	case SourceAddressMode:
+		return "SAM"
+	case DestinationAddressMode:
+		return "DAM"
+	}
+
+	return "Unknown"
+}
+
+// Message is a decoded MPDO.
+type Message struct {
+	Mode AddressMode
+	// NodeID is the node id of the producer (SAM) or the consumer (DAM), 0 addresses all consumers in DAM
+	NodeID      uint8
+	ObjectIndex canopen.ObjectIndex
+	Data        [4]byte
+}
+
+// Decode returns the MPDO of a frame, the frame must contain 8 data bytes.
+func Decode(frame canopen.Frame) (Message, error) {
+	if n := len(frame.Data); n != 8 {
+		return Message{}, fmt.Errorf("Invalid data length %d", n)
+	}
+
+	message := Message{
+		Mode:        SourceAddressMode,
+		NodeID:      frame.Data[0] & canopen.MaskNodeID,
+		ObjectIndex: canopen.NewObjectIndex(uint16(frame.Data[1])|uint16(frame.Data[2])<<8, frame.Data[3]),
+	}
+
+	if frame.Data[0]&canopen.MPDO != 0 {
+		message.Mode = DestinationAddressMode
+	}
+
+	copy(message.Data[:], frame.Data[4:8])
+	return message, nil
+}
+
+// Frame returns the frame of the MPDO with the COB-ID.
+func (message Message) Frame(cobID uint16) canopen.Frame {
+	addressByte := message.NodeID & canopen.MaskNodeID
+	if message.Mode == DestinationAddressMode {
+		addressByte |= canopen.MPDO
+	}
+
+	return canopen.NewFrame(cobID, []byte{
+		addressByte,
+		message.ObjectIndex.Index.B0, message.ObjectIndex.Index.B1,
+		message.ObjectIndex.SubIndex,
+		message.Data[0], message.Data[1], message.Data[2], message.Data[3],
+	})
+}
+
+// Send publishes the MPDO with the COB-ID.
+func (message Message) Send(bus *can.Bus, cobID uint16) error {
+	return bus.PublishMinDuration(message.Frame(cobID).CANFrame(), 0)
+}
@@ -0,0 +1,333 @@
+package mpdo
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"testing"
+	"time"
+)
+
+func getObjectDictionary() *od.ObjectDictionary {
+	dictionary := od.NewObjectDictionary()
+	values := od.NewRecord(0x2000, "Values")
+	for _, variable := range []*od.Variable{
+		{Name: "Mode", SubIndex: 1, DataType: sdo.DATA_TYPE_UNSIGNED_8, DefaultValue: []byte{0x05}},
+		{Name: "Speed", SubIndex: 2, DataType: sdo.DATA_TYPE_INTEGER_16, DefaultValue: []byte{0x34, 0x12}},
+		{Name: "Position", SubIndex: 3, DataType: sdo.DATA_TYPE_UNSIGNED_32, DefaultValue: []byte{0x78, 0x56, 0x34, 0x12}},
+		{Name: "Counter", SubIndex: 4, DataType: sdo.DATA_TYPE_UNSIGNED_64, DefaultValue: make([]byte, 8)},
+	} {
+		variable.AccessType = od.ACCESS_TYPE_RW
+		values.AddSubIndex(variable)
+	}
+	dictionary.Add(values)
+	dictionary.Add(od.NewVariable(0x2001, "Read only", sdo.DATA_TYPE_UNSIGNED_8, od.ACCESS_TYPE_RO, []byte{0}))
+
+	// Sub indexes 1-3 of 0x2000 may be sent with SAM
+	dictionary.Add(od.NewArray(ScannerListIndex, "Object scanner list", sdo.DATA_TYPE_UNSIGNED_32, od.ACCESS_TYPE_RW, 2))
+	scanner := ScannerEntry{Index: 0x2000, SubIndex: 1, BlockSize: 3}
+	dictionary.SetValue(canopen.NewObjectIndex(ScannerListIndex, 1), binary.LittleEndian.AppendUint32(nil, scanner.Entry()))
+
+	// Sub indexes 1-3 of 0x2000 of node 5 are written to 0x2000 sub indexes 1-3
+	dictionary.Add(od.NewArray(DispatcherListIndex, "Object dispatcher list", sdo.DATA_TYPE_UNSIGNED_64, od.ACCESS_TYPE_RW, 1))
+	dispatcher := DispatcherEntry{SenderNodeID: 5, SenderIndex: 0x2000, SenderSubIndex: 1, LocalIndex: 0x2000, LocalSubIndex: 1, BlockSize: 3}
+	dictionary.SetValue(canopen.NewObjectIndex(DispatcherListIndex, 1), binary.LittleEndian.AppendUint64(nil, dispatcher.Entry()))
+
+	return dictionary
+}
+
+func TestMessage(t *testing.T) {
+	message := Message{
+		Mode:        DestinationAddressMode,
+		NodeID:      3,
+		ObjectIndex: canopen.NewObjectIndex(0x2000, 2),
+		Data:        [4]byte{1, 2, 3, 4},
+	}
+
+	frame := message.Frame(0x180)
+	if expected := []byte{0x83, 0x00, 0x20, 0x02, 1, 2, 3, 4}; !bytes.Equal(frame.Data, expected) {
+		t.Log("Unexpected data", frame.Data, "expected", expected)
+		t.FailNow()
+	}
+
+	if decoded, err := Decode(frame); err != nil || decoded != message {
+		t.Log("Unexpected message", decoded, err)
+		t.FailNow()
+	}
+
+	if _, err := Decode(canopen.NewFrame(0x180, []byte{0x83, 0x00, 0x20})); err == nil {
+		t.Log("Expected length error")
+		t.FailNow()
+	}
+}
+
+func TestLists(t *testing.T) {
+	scanner := ScannerEntry{Index: 0x2000, SubIndex: 1, BlockSize: 3}
+	if entry := scanner.Entry(); entry != 0x03200001 || ParseScannerEntry(entry) != scanner {
+		t.Log("Unexpected scanner entry", entry)
+		t.FailNow()
+	}
+
+	dispatcher := DispatcherEntry{SenderNodeID: 5, SenderIndex: 0x6000, SenderSubIndex: 1, LocalIndex: 0x2000, LocalSubIndex: 2, BlockSize: 4}
+	if entry := dispatcher.Entry(); entry != 0x0420000260000105 || ParseDispatcherEntry(entry) != dispatcher {
+		t.Log("Unexpected dispatcher entry", entry)
+		t.FailNow()
+	}
+
+	objectIndex, ok := dispatcher.Map(Message{NodeID: 5, ObjectIndex: canopen.NewObjectIndex(0x6000, 3)})
+	if !ok || !objectIndex.Compare(canopen.NewObjectIndex(0x2000, 4)) {
+		t.Log("Unexpected mapped object", objectIndex.String(), ok)
+		t.FailNow()
+	}
+
+	for _, message := range []Message{
+		{NodeID: 6, ObjectIndex: canopen.NewObjectIndex(0x6000, 1)},
+		{NodeID: 5, ObjectIndex: canopen.NewObjectIndex(0x6000, 5)},
+		{NodeID: 5, ObjectIndex: canopen.NewObjectIndex(0x6000, 1), Mode: DestinationAddressMode},
+	} {
+		if _, ok := dispatcher.Map(message); ok {
+			t.Log("Unexpected mapping", message)
+			t.FailNow()
+		}
+	}
+
+	dictionary := getObjectDictionary()
+	if list, err := ReadScannerList(dictionary); err != nil || len(list) != 1 || list[0] != scanner {
+		t.Log("Unexpected scanner list", list, err)
+		t.FailNow()
+	}
+
+	if list, err := ReadDispatcherList(dictionary); err != nil || len(list) != 1 || list[0].SenderNodeID != 5 {
+		t.Log("Unexpected dispatcher list", list, err)
+		t.FailNow()
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	dictionary := getObjectDictionary()
+
+	received := make(chan canopen.ObjectIndex, 10)
+	errs := make(chan error, 10)
+	dispatcher := &Dispatcher{
+		NodeID:           1,
+		CobID:            0x201,
+		ObjectDictionary: dictionary,
+		OnReceive: func(message Message, objectIndex canopen.ObjectIndex) {
+			received <- objectIndex
+		},
+		OnError: func(message Message, err error) {
+			errs <- err
+		},
+	}
+	if err := dispatcher.Start(bus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer dispatcher.Stop()
+
+	speed := canopen.NewObjectIndex(0x2000, 2)
+	for _, test := range []struct {
+		message  Message
+		expected []byte
+	}{
+		// DAM to the node and to all nodes
+		{Message{Mode: DestinationAddressMode, NodeID: 1, ObjectIndex: speed, Data: [4]byte{0x01, 0x02, 0xFF, 0xFF}}, []byte{0x01, 0x02}},
+		{Message{Mode: DestinationAddressMode, NodeID: 0, ObjectIndex: speed, Data: [4]byte{0x03, 0x04}}, []byte{0x03, 0x04}},
+		// SAM of node 5 mapped by the dispatcher list
+		{Message{Mode: SourceAddressMode, NodeID: 5, ObjectIndex: speed, Data: [4]byte{0x05, 0x06}}, []byte{0x05, 0x06}},
+	} {
+		bus.PublishLocal(test.message.Frame(0x201).CANFrame())
+		if objectIndex := <-received; !objectIndex.Compare(speed) {
+			t.Log("Unexpected object", objectIndex.String())
+			t.FailNow()
+		}
+
+		if value, _ := dictionary.Value(speed); !bytes.Equal(value, test.expected) {
+			t.Log("Unexpected value", value, "expected", test.expected)
+			t.FailNow()
+		}
+	}
+
+	// Ignored: DAM to another node, SAM of an unmapped node, other COB-ID
+	for _, message := range []Message{
+		{Mode: DestinationAddressMode, NodeID: 2, ObjectIndex: speed},
+		{Mode: SourceAddressMode, NodeID: 6, ObjectIndex: speed},
+	} {
+		if err := dispatcher.Dispatch(message); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+	bus.PublishLocal(Message{Mode: DestinationAddressMode, ObjectIndex: speed}.Frame(0x202).CANFrame())
+	if value, _ := dictionary.Value(speed); !bytes.Equal(value, []byte{0x05, 0x06}) {
+		t.Log("Ignored MPDO was written", value)
+		t.FailNow()
+	}
+
+	if err := dispatcher.Dispatch(Message{Mode: DestinationAddressMode, ObjectIndex: canopen.NewObjectIndex(0x2000, 4)}); !errors.Is(err, ErrObjectSize) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	for _, objectIndex := range []canopen.ObjectIndex{canopen.NewObjectIndex(0x2001, 0), canopen.NewObjectIndex(0x2002, 0)} {
+		bus.PublishLocal(Message{Mode: DestinationAddressMode, ObjectIndex: objectIndex}.Frame(0x201).CANFrame())
+		if err := <-errs; err == nil {
+			t.Log("Expected error for", objectIndex.String())
+			t.FailNow()
+		}
+	}
+}
+
+func TestSender(t *testing.T) {
+	sender := &Sender{NodeID: 5, CobID: 0x185, ObjectDictionary: getObjectDictionary()}
+	if err := sender.SendSAM(nil, canopen.NewObjectIndex(0x2000, 4)); err == nil {
+		t.Log("Object outside of the scanner list was sent")
+		t.FailNow()
+	}
+
+	if err := sender.SendSAM(nil, canopen.NewObjectIndex(0x2001, 0)); err == nil {
+		t.Log("Object outside of the scanner list was sent")
+		t.FailNow()
+	}
+}
+
+func TestConsumer(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	speed := canopen.NewObjectIndex(0x2000, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer := Consumer{ObjectIndex: speed, ObserveCobID: 0x185, ReceiveCobID: 5, BufferSize: 2}
+	sub := consumer.Subscribe(ctx, bus)
+
+	for i := byte(1); i <= 3; i++ {
+		bus.PublishLocal(Message{NodeID: 5, ObjectIndex: speed, Data: [4]byte{i}}.Frame(0x185).CANFrame())
+	}
+	// Ignored: other object, other node, other COB-ID, invalid length
+	bus.PublishLocal(Message{NodeID: 5, ObjectIndex: canopen.NewObjectIndex(0x2000, 3)}.Frame(0x185).CANFrame())
+	bus.PublishLocal(Message{NodeID: 6, ObjectIndex: speed}.Frame(0x185).CANFrame())
+	bus.PublishLocal(Message{NodeID: 5, ObjectIndex: speed}.Frame(0x186).CANFrame())
+	bus.PublishLocal(canopen.NewFrame(0x185, []byte{0x05, 0x00, 0x20, 0x02}).CANFrame())
+
+	for i := byte(1); i <= 2; i++ {
+		sample := <-sub.C
+		if sample.CobID != 0x185 || sample.Data != [4]byte{i} || sample.Time.IsZero() {
+			t.Log("Unexpected sample", sample)
+			t.FailNow()
+		}
+	}
+
+	if sub.Received() != 2 || sub.Dropped() != 1 || sub.Invalid() != 1 {
+		t.Log("Unexpected statistics", sub.Received(), sub.Dropped(), sub.Invalid())
+		t.FailNow()
+	}
+
+	cancel()
+	select {
+	case _, ok := <-sub.C:
+		if ok {
+			t.Log("Unexpected sample after cancel")
+			t.FailNow()
+		}
+	case <-time.After(time.Second):
+		t.Log("Subscription not closed")
+		t.FailNow()
+	}
+
+	// Closing twice is allowed and frames after closing are ignored
+	sub.Close()
+	bus.PublishLocal(Message{NodeID: 5, ObjectIndex: speed}.Frame(0x185).CANFrame())
+	if sub.Received() != 2 {
+		t.Log("Sample received after close")
+		t.FailNow()
+	}
+}
+
+func TestProducerContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	producer := Producer{ObjectIndex: canopen.NewObjectIndex(0x2000, 2), RequestCobID: 0x185, ReceiveCobID: 5}
+	if err := producer.DoContext(ctx, can.NewBus(nil, "test")); !errors.Is(err, context.Canceled) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
+
+func TestProducerMessage(t *testing.T) {
+	for _, test := range []struct {
+		producer Producer
+		expected byte
+	}{
+		{Producer{ReceiveCobID: 5}, 0x05},
+		{Producer{ReceiveCobID: 5, Mode: DestinationAddressMode}, 0x85},
+		// Bit 7 of the address byte selects DAM without a mode
+		{Producer{ReceiveCobID: 0x85}, 0x85},
+		{Producer{ReceiveCobID: 0x80}, 0x80},
+	} {
+		if frame := test.producer.message().Frame(0x185); frame.Data[0] != test.expected {
+			t.Log("Unexpected address byte", test.producer.ReceiveCobID, test.producer.Mode, frame.Data[0])
+			t.FailNow()
+		}
+	}
+}
+
+func TestConsumerDestinationAddress(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	speed := canopen.NewObjectIndex(0x2000, 2)
+
+	// Bit 7 of the address byte selects DAM for the producer and the consumer
+	consumer := Consumer{ObjectIndex: speed, ObserveCobID: 0x185, ReceiveCobID: 0x85}
+	sub := consumer.Subscribe(context.Background(), bus)
+	defer sub.Close()
+
+	channel := make(chan [4]byte, 4)
+	consumer.Listen(bus, channel)
+
+	for _, producer := range []Producer{
+		{ObjectIndex: speed, RequestCobID: 0x185, ReceiveCobID: 0x85, Data: [4]byte{1}},
+		{ObjectIndex: speed, RequestCobID: 0x185, ReceiveCobID: 5, Mode: DestinationAddressMode, Data: [4]byte{2}},
+		// DAM to all nodes
+		{ObjectIndex: speed, RequestCobID: 0x185, ReceiveCobID: 0x80, Data: [4]byte{3}},
+	} {
+		bus.PublishLocal(producer.message().Frame(producer.RequestCobID).CANFrame())
+	}
+	// Ignored: SAM of node 5, DAM to another node
+	bus.PublishLocal(Message{NodeID: 5, ObjectIndex: speed}.Frame(0x185).CANFrame())
+	bus.PublishLocal(Message{Mode: DestinationAddressMode, NodeID: 6, ObjectIndex: speed}.Frame(0x185).CANFrame())
+
+	for i := byte(1); i <= 3; i++ {
+		if sample := <-sub.C; sample.Data != [4]byte{i} {
+			t.Log("Unexpected sample", sample)
+			t.FailNow()
+		}
+
+		select {
+		case data := <-channel:
+			if data != [4]byte{i} {
+				t.Log("Unexpected listened data", data)
+				t.FailNow()
+			}
+		case <-time.After(time.Second):
+			t.Log("Listen did not deliver", i)
+			t.FailNow()
+		}
+	}
+
+	if sub.Received() != 3 {
+		t.Log("Unexpected number of samples", sub.Received())
+		t.FailNow()
+	}
+
+	// SAM consumers ignore DAM broadcasts
+	sam := Consumer{ObjectIndex: speed, ObserveCobID: 0x185, ReceiveCobID: 5}
+	if sam.accepts(Message{Mode: DestinationAddressMode, ObjectIndex: speed}) || sam.accepts(Message{ObjectIndex: speed}) {
+		t.Log("SAM consumer accepted a message of another node")
+		t.FailNow()
+	}
+}
@@ -1,6 +1,8 @@
 package mpdo
 
 import (
+	"context"
+	"fmt"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
 	"strconv"
@@ -13,25 +15,43 @@ type Producer struct {
 	Data         [4]byte
 	RequestCobID uint16
 	ReceiveCobID uint8
+	// Mode is the address mode, with DestinationAddressMode ReceiveCobID is the node id of the consumer.
+	// If bit 7 of ReceiveCobID is set, the MPDO is always sent in the destination address mode.
+	Mode AddressMode
 }
 
 func (producer Producer) Do(bus *can.Bus) error {
+	return producer.DoContext(context.Background(), bus)
+}
+
+// DoContext sends the MPDO unless ctx is done before it could be sent.
+// The returned error wraps context.DeadlineExceeded or context.Canceled.
+func (producer Producer) DoContext(ctx context.Context, bus *can.Bus) error {
 	// Do not allow multiple messages for the same device
 	key := strconv.Itoa(int(producer.ReceiveCobID))
-	canopen.Lock.Lock(key)
+	if err := canopen.LockContext(ctx, key); err != nil {
+		return err
+	}
 	defer canopen.Lock.Unlock(key)
 
-	return bus.Publish(can.Frame{
-		ID:     uint32(producer.RequestCobID),
-		Length: 8,
-		Data: [8]byte{
-			producer.ReceiveCobID,
-			producer.ObjectIndex.Index.B0, producer.ObjectIndex.Index.B1,
-			producer.ObjectIndex.SubIndex,
-			producer.Data[0],
-			producer.Data[1],
-			producer.Data[2],
-			producer.Data[3],
-		},
-	})
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("sending MPDO %s: %w", producer.ObjectIndex.String(), err)
+	}
+
+	return bus.Publish(producer.message().Frame(producer.RequestCobID).CANFrame())
+}
+
+func (producer Producer) message() Message {
+	// Bit 7 of the address byte selects the destination address mode, as before the Mode was added
+	mode := producer.Mode
+	if producer.ReceiveCobID&canopen.MPDO != 0 {
+		mode = DestinationAddressMode
+	}
+
+	return Message{
+		Mode:        mode,
+		NodeID:      producer.ReceiveCobID & canopen.MaskNodeID,
+		ObjectIndex: producer.ObjectIndex,
+		Data:        producer.Data,
+	}
 }
@@ -0,0 +1,72 @@
+package mpdo
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+)
+
+// Sender sends MPDOs of a local node.
+type Sender struct {
+	NodeID uint8
+	// CobID is the COB-ID of the MPDOs (the COB-ID of the TPDO)
+	CobID            uint16
+	ObjectDictionary *od.ObjectDictionary
+	// ScannerList contains the objects which may be sent with SAM, it is read from the object dictionary (0x1FA0-0x1FCF) if nil
+	ScannerList []ScannerEntry
+}
+
+// SendSAM sends the current value of a local object with the source address mode.
+// The object must be part of the object scanner list.
+func (sender *Sender) SendSAM(bus *can.Bus, objectIndex canopen.ObjectIndex) error {
+	if sender.ScannerList == nil {
+		list, err := ReadScannerList(sender.ObjectDictionary)
+		if err != nil {
+			return err
+		}
+		sender.ScannerList = list
+	}
+
+	scanned := false
+	for _, entry := range sender.ScannerList {
+		if entry.Contains(objectIndex) {
+			scanned = true
+			break
+		}
+	}
+
+	if !scanned {
+		return fmt.Errorf("object %s is not in the object scanner list", objectIndex.String())
+	}
+
+	data, abortCode := sender.ObjectDictionary.Value(objectIndex)
+	if abortCode != canopen.NO_ERROR {
+		return fmt.Errorf("reading %s: %s", objectIndex.String(), canopen.GetAbortCodeText(abortCode))
+	}
+
+	message := Message{
+		Mode:        SourceAddressMode,
+		NodeID:      sender.NodeID,
+		ObjectIndex: objectIndex,
+	}
+
+	if len(data) > len(message.Data) {
+		return fmt.Errorf("reading %s: %w", objectIndex.String(), ErrObjectSize)
+	}
+	copy(message.Data[:], data)
+
+	return message.Send(bus, sender.CobID)
+}
+
+// SendDAM writes data to an object of the destination node (0 for all nodes) with the destination address mode.
+func (sender *Sender) SendDAM(bus *can.Bus, destination uint8, objectIndex canopen.ObjectIndex, data [4]byte) error {
+	message := Message{
+		Mode:        DestinationAddressMode,
+		NodeID:      destination,
+		ObjectIndex: objectIndex,
+		Data:        data,
+	}
+
+	return message.Send(bus, sender.CobID)
+}
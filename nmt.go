@@ -1,16 +1,56 @@
 package canopen
 
+import "fmt"
+
+// NMTCommand is the command specifier of an NMT message
+type NMTCommand uint8
+
 const (
-	GoToOperational        uint8 = 0x1
-	GoToStopped            uint8 = 0x2
-	GoToPreOperation       uint8 = 0x80
-	GoToResetNode          uint8 = 0x81
-	GoToResetCommunication uint8 = 0x82
+	GoToOperational        NMTCommand = 0x1
+	GoToStopped            NMTCommand = 0x2
+	GoToPreOperation       NMTCommand = 0x80
+	GoToResetNode          NMTCommand = 0x81
+	GoToResetCommunication NMTCommand = 0x82
 )
 
+// NMTState is the state of a node as reported by heartbeat and boot-up messages
+type NMTState uint8
+
 const (
-	BootUp         uint8 = 0x0
-	Stopped        uint8 = 0x04
-	Operational    uint8 = 0x05
-	PreOperational uint8 = 0x7f
+	BootUp         NMTState = 0x0
+	Stopped        NMTState = 0x04
+	Operational    NMTState = 0x05
+	PreOperational NMTState = 0x7f
 )
+
+func (command NMTCommand) String() string {
+	switch command {
+	case GoToOperational:
+		return "Start remote node"
+	case GoToStopped:
+		return "Stop remote node"
+	case GoToPreOperation:
+		return "Enter pre-operational"
+	case GoToResetNode:
+		return "Reset node"
+	case GoToResetCommunication:
+		return "Reset communication"
+	}
+
+	return fmt.Sprintf("Unknown command %X", uint8(command))
+}
+
+func (state NMTState) String() string {
+	switch state {
+	case BootUp:
+		return "Boot-up"
+	case Stopped:
+		return "Stopped"
+	case Operational:
+		return "Operational"
+	case PreOperational:
+		return "Pre-operational"
+	}
+
+	return fmt.Sprintf("Unknown state %X", uint8(state))
+}
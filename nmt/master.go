@@ -0,0 +1,151 @@
+package nmt
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// Master sends NMT commands and tracks the state of every node
+// from the heartbeat and boot-up messages on the bus.
+type Master struct {
+	bus     *can.Bus
+	handler can.Handler
+
+	lock    sync.Mutex
+	states  map[uint8]canopen.NMTState
+	changed chan struct{}
+	// updates counts the received states, updated contains the count at the last state of a node
+	updates uint64
+	updated map[uint8]uint64
+}
+
+// NewMaster returns a master which listens to the heartbeat messages of the bus.
+func NewMaster(bus *can.Bus) *Master {
+	master := &Master{
+		bus:     bus,
+		states:  map[uint8]canopen.NMTState{},
+		changed: make(chan struct{}),
+		updated: map[uint8]uint64{},
+	}
+
+	master.handler = can.NewHandler(master.handle)
+	bus.Subscribe(master.handler)
+
+	return master
+}
+
+// Close stops listening to the heartbeat messages.
+func (master *Master) Close() {
+	master.bus.Unsubscribe(master.handler)
+}
+
+// Send sends a command to a node, node id 0 sends the command to all nodes.
+func (master *Master) Send(command canopen.NMTCommand, nodeID uint8) error {
+	if nodeID > canopen.MaxNodeID {
+		return fmt.Errorf("invalid node id %d", nodeID)
+	}
+
+	frame := canopen.NewFrame(canopen.MessageTypeNMT, []byte{byte(command), nodeID})
+	return master.bus.Publish(frame.CANFrame())
+}
+
+// State returns the last known state of a node.
+func (master *Master) State(nodeID uint8) (canopen.NMTState, bool) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	state, ok := master.states[nodeID]
+	return state, ok
+}
+
+// States returns the last known state of all nodes which were seen on the bus.
+func (master *Master) States() map[uint8]canopen.NMTState {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	states := make(map[uint8]canopen.NMTState, len(master.states))
+	for nodeID, state := range master.states {
+		states[nodeID] = state
+	}
+
+	return states
+}
+
+// WaitForState waits until all nodes report the state.
+// If the nodes don't confirm the state on time, an error is returned.
+func (master *Master) WaitForState(nodeIDs []uint8, state canopen.NMTState, timeout time.Duration) error {
+	return master.waitForState(nodeIDs, state, 0, timeout)
+}
+
+// SendAndWait sends a command to every node and waits until they report the state.
+// Only states received after the command was sent confirm it.
+func (master *Master) SendAndWait(command canopen.NMTCommand, nodeIDs []uint8, state canopen.NMTState, timeout time.Duration) error {
+	master.lock.Lock()
+	after := master.updates
+	master.lock.Unlock()
+
+	for _, nodeID := range nodeIDs {
+		if nodeID == 0 {
+			return fmt.Errorf("invalid node id %d", nodeID)
+		}
+
+		if err := master.Send(command, nodeID); err != nil {
+			return err
+		}
+	}
+
+	return master.waitForState(nodeIDs, state, after, timeout)
+}
+
+// waitForState waits until all nodes report the state in a message received after the update count
+func (master *Master) waitForState(nodeIDs []uint8, state canopen.NMTState, after uint64, timeout time.Duration) error {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		master.lock.Lock()
+		var pending []uint8
+		for _, nodeID := range nodeIDs {
+			if current, ok := master.states[nodeID]; !ok || current != state || master.updated[nodeID] <= after {
+				pending = append(pending, nodeID)
+			}
+		}
+		changed := master.changed
+		master.lock.Unlock()
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return fmt.Errorf("timeout waiting for nodes %v to enter state %s", pending, state)
+		}
+	}
+}
+
+func (master *Master) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.MessageType() != canopen.MessageTypeHeartbeat || frame.NodeID() == 0 || frm.Length < 1 {
+		return
+	}
+
+	master.setState(frame.NodeID(), canopen.NMTState(frame.Data[0]&0x7F))
+}
+
+func (master *Master) setState(nodeID uint8, state canopen.NMTState) {
+	master.lock.Lock()
+	defer master.lock.Unlock()
+
+	master.states[nodeID] = state
+	master.updates++
+	master.updated[nodeID] = master.updates
+
+	// Wake up all waiting callers
+	close(master.changed)
+	master.changed = make(chan struct{})
+}
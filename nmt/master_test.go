@@ -0,0 +1,160 @@
+package nmt
+
+import (
+	"bytes"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"testing"
+	"time"
+)
+
+// busPair returns the bus of the master and the bus of the nodes connected by a pipe
+func busPair(t *testing.T) (*can.Bus, *can.Bus) {
+	a, b := net.Pipe()
+	masterBus := can.NewBus(can.NewReadWriteCloser(a), "master")
+	nodeBus := can.NewBus(can.NewReadWriteCloser(b), "node")
+	go masterBus.ConnectAndPublish()
+	go nodeBus.ConnectAndPublish()
+	t.Cleanup(func() {
+		masterBus.Disconnect()
+		nodeBus.Disconnect()
+	})
+
+	return masterBus, nodeBus
+}
+
+func heartbeat(nodeID uint8, state canopen.NMTState) can.Frame {
+	return canopen.NewFrame(canopen.MessageTypeHeartbeat+uint16(nodeID), []byte{byte(state)}).CANFrame()
+}
+
+func TestSend(t *testing.T) {
+	masterBus, nodeBus := busPair(t)
+	master := NewMaster(masterBus)
+	defer master.Close()
+
+	sub := canopen.Subscribe(nodeBus, canopen.MessageTypeNMT, 10)
+	defer sub.Close()
+
+	if err := master.Send(canopen.GoToResetNode, 5); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if frame, err := sub.Receive(time.Second); err != nil || !bytes.Equal(frame.Data[:2], []byte{0x81, 5}) {
+		t.Log("Unexpected frame", frame.Data, err)
+		t.FailNow()
+	}
+
+	if err := master.Send(canopen.GoToOperational, canopen.MaxNodeID+1); err == nil {
+		t.Log("Expected invalid node id")
+		t.FailNow()
+	}
+}
+
+func TestState(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	master := NewMaster(bus)
+	defer master.Close()
+
+	bus.PublishLocal(heartbeat(1, canopen.BootUp))
+	bus.PublishLocal(heartbeat(2, canopen.PreOperational))
+	// The toggle bit of node guarding responses is ignored
+	bus.PublishLocal(heartbeat(3, 0x80|canopen.Operational))
+	bus.PublishLocal(heartbeat(1, canopen.Stopped))
+	// Frames of other services are ignored
+	bus.PublishLocal(canopen.NewFrame(0x184, []byte{0x05}).CANFrame())
+
+	if state, ok := master.State(3); !ok || state != canopen.Operational {
+		t.Log("Unexpected state", state, ok)
+		t.FailNow()
+	}
+
+	if _, ok := master.State(4); ok {
+		t.Log("Unexpected state of unknown node")
+		t.FailNow()
+	}
+
+	states := master.States()
+	if len(states) != 3 || states[1] != canopen.Stopped || states[2] != canopen.PreOperational {
+		t.Log("Unexpected states", states)
+		t.FailNow()
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	master := NewMaster(bus)
+	defer master.Close()
+
+	bus.PublishLocal(heartbeat(1, canopen.PreOperational))
+	if err := master.WaitForState([]uint8{1, 2}, canopen.PreOperational, 50*time.Millisecond); err == nil {
+		t.Log("Expected timeout")
+		t.FailNow()
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		bus.PublishLocal(heartbeat(2, canopen.PreOperational))
+	}()
+
+	if err := master.WaitForState([]uint8{1, 2}, canopen.PreOperational, time.Second); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestSendAndWait(t *testing.T) {
+	masterBus, nodeBus := busPair(t)
+	master := NewMaster(masterBus)
+	defer master.Close()
+
+	// Node 3 is already operational before the command is sent
+	_ = nodeBus.Publish(heartbeat(3, canopen.Operational))
+	if err := master.WaitForState([]uint8{3}, canopen.Operational, time.Second); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Node 3 confirms commands after a delay, node 4 doesn't answer
+	commands := canopen.Subscribe(nodeBus, canopen.MessageTypeNMT, 10)
+	defer commands.Close()
+	received := make(chan []byte, 10)
+	go func() {
+		for {
+			frame, err := commands.Receive(time.Second)
+			if err != nil {
+				return
+			}
+
+			received <- frame.Data
+			if frame.Data[1] == 3 && canopen.NMTCommand(frame.Data[0]) == canopen.GoToOperational {
+				time.Sleep(50 * time.Millisecond)
+				_ = nodeBus.Publish(heartbeat(3, canopen.Operational))
+			}
+		}
+	}()
+
+	start := time.Now()
+	if err := master.SendAndWait(canopen.GoToOperational, []uint8{3}, canopen.Operational, time.Second); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// The state before the command doesn't confirm it
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Log("Command confirmed without new heartbeat", elapsed)
+		t.FailNow()
+	}
+
+	// The command is sent to the node instead of all nodes
+	if data := <-received; !bytes.Equal(data[:2], []byte{byte(canopen.GoToOperational), 3}) {
+		t.Log("Unexpected command", data)
+		t.FailNow()
+	}
+
+	if err := master.SendAndWait(canopen.GoToOperational, []uint8{3, 4}, canopen.Operational, 200*time.Millisecond); err == nil {
+		t.Log("Expected timeout of node 4")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,163 @@
+package od
+
+import (
+	"github.com/FabianPetersen/canopen/sdo"
+	"sort"
+	"sync"
+)
+
+// ObjectType defines the kind of an object as specified in CiA 301
+type ObjectType byte
+
+const (
+	OBJECT_TYPE_NULL      ObjectType = 0x00
+	OBJECT_TYPE_DOMAIN    ObjectType = 0x02
+	OBJECT_TYPE_DEFTYPE   ObjectType = 0x05
+	OBJECT_TYPE_DEFSTRUCT ObjectType = 0x06
+	OBJECT_TYPE_VAR       ObjectType = 0x07
+	OBJECT_TYPE_ARRAY     ObjectType = 0x08
+	OBJECT_TYPE_RECORD    ObjectType = 0x09
+)
+
+// AccessType defines if an object can be read or written over SDO
+type AccessType byte
+
+const (
+	// ACCESS_TYPE_RW can be read and written
+	ACCESS_TYPE_RW AccessType = iota
+	// ACCESS_TYPE_RO can only be read
+	ACCESS_TYPE_RO
+	// ACCESS_TYPE_WO can only be written
+	ACCESS_TYPE_WO
+	// ACCESS_TYPE_RWR can be read and written, it is mapped into TPDOs
+	ACCESS_TYPE_RWR
+	// ACCESS_TYPE_RWW can be read and written, it is mapped into RPDOs
+	ACCESS_TYPE_RWW
+	// ACCESS_TYPE_CONST can only be read and never changes
+	ACCESS_TYPE_CONST
+)
+
+// IsReadable checks if the value can be read over SDO
+func (accessType AccessType) IsReadable() bool {
+	return accessType != ACCESS_TYPE_WO
+}
+
+// IsWritable checks if the value can be written over SDO
+func (accessType AccessType) IsWritable() bool {
+	return accessType != ACCESS_TYPE_RO && accessType != ACCESS_TYPE_CONST
+}
+
+// Variable represents a single value of the object dictionary, addressed by index and sub index.
+type Variable struct {
+	Name       string
+	SubIndex   uint8
+	DataType   sdo.SDODataType
+	AccessType AccessType
+
+	// DefaultValue is the initial value of the variable
+	DefaultValue []byte
+	// LowLimit is the minimal value which can be written (optional)
+	LowLimit []byte
+	// HighLimit is the maximal value which can be written (optional)
+	HighLimit []byte
+
+	// PDOMapping indicates if the variable can be mapped into a PDO
+	PDOMapping bool
+
+	// Relative indicates that the default value includes the node ID ($NODEID in an EDS)
+	Relative bool
+
+	value []byte
+	// writeLock serializes the writes of the variable, so the value approved by the write hook is stored
+	writeLock sync.Mutex
+}
+
+// Object represents an index of the object dictionary.
+// A VAR object contains the value at sub index 0, ARRAY and RECORD objects contain multiple sub indexes.
+type Object struct {
+	Index      uint16
+	Name       string
+	ObjectType ObjectType
+
+	subIndexes map[uint8]*Variable
+}
+
+// NewObject returns an object without any sub indexes.
+func NewObject(index uint16, name string, objectType ObjectType) *Object {
+	return &Object{
+		Index:      index,
+		Name:       name,
+		ObjectType: objectType,
+		subIndexes: map[uint8]*Variable{},
+	}
+}
+
+// NewVariable returns a VAR object with a single value at sub index 0.
+func NewVariable(index uint16, name string, dataType sdo.SDODataType, accessType AccessType, defaultValue []byte) *Object {
+	object := NewObject(index, name, OBJECT_TYPE_VAR)
+	object.AddSubIndex(&Variable{
+		Name:         name,
+		SubIndex:     0,
+		DataType:     dataType,
+		AccessType:   accessType,
+		DefaultValue: defaultValue,
+	})
+
+	return object
+}
+
+// NewArray returns an ARRAY object with sub index 0 (highest sub index) and length sub indexes of the same data type.
+func NewArray(index uint16, name string, dataType sdo.SDODataType, accessType AccessType, length uint8) *Object {
+	object := NewObject(index, name, OBJECT_TYPE_ARRAY)
+	object.AddSubIndex(&Variable{
+		Name:         "Highest sub-index supported",
+		SubIndex:     0,
+		DataType:     sdo.DATA_TYPE_UNSIGNED_8,
+		AccessType:   ACCESS_TYPE_CONST,
+		DefaultValue: []byte{length},
+	})
+
+	for i := uint8(1); i <= length && i != 0; i++ {
+		object.AddSubIndex(&Variable{
+			Name:         name,
+			SubIndex:     i,
+			DataType:     dataType,
+			AccessType:   accessType,
+			DefaultValue: make([]byte, sdo.DataTypeSize(dataType)),
+		})
+	}
+
+	return object
+}
+
+// NewRecord returns an empty RECORD object, the sub indexes are added with AddSubIndex.
+func NewRecord(index uint16, name string) *Object {
+	return NewObject(index, name, OBJECT_TYPE_RECORD)
+}
+
+// AddSubIndex adds or replaces a variable of the object.
+// The current value of the variable is initialized with the default value.
+func (object *Object) AddSubIndex(variable *Variable) *Object {
+	variable.value = append([]byte{}, variable.DefaultValue...)
+	object.subIndexes[variable.SubIndex] = variable
+	return object
+}
+
+// SubIndex returns the variable at the sub index or nil if it does not exist.
+func (object *Object) SubIndex(subIndex uint8) *Variable {
+	return object.subIndexes[subIndex]
+}
+
+// SubIndexes returns all variables of the object ordered by sub index.
+func (object *Object) SubIndexes() []*Variable {
+	variables := make([]*Variable, 0, len(object.subIndexes))
+	for _, variable := range object.subIndexes {
+		variables = append(variables, variable)
+	}
+
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].SubIndex < variables[j].SubIndex
+	})
+
+	return variables
+}
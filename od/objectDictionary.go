@@ -0,0 +1,275 @@
+package od
+
+import (
+	"encoding/binary"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"math"
+	"sort"
+	"sync"
+)
+
+// ObjectDictionary contains all objects of a CANopen device.
+// The Read and Write methods can be used as Upload and Download callbacks of an SDO server.
+type ObjectDictionary struct {
+	lock    sync.RWMutex
+	objects map[uint16]*Object
+	hooks   map[uint16]WriteHook
+}
+
+// WriteHook is called when an object is written by an SDO download, after the value was validated and before it is stored.
+// The hook can act on the new value or reject it by returning an abort code.
+// It must not write the object itself with Write, because writes of an object are serialized.
+type WriteHook func(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode
+
+// NewObjectDictionary returns an empty object dictionary.
+func NewObjectDictionary() *ObjectDictionary {
+	return &ObjectDictionary{
+		objects: map[uint16]*Object{},
+		hooks:   map[uint16]WriteHook{},
+	}
+}
+
+// OnWrite sets the hook which is called when a sub index of the object at the index is written, nil removes the hook.
+func (od *ObjectDictionary) OnWrite(index uint16, hook WriteHook) {
+	od.lock.Lock()
+	defer od.lock.Unlock()
+
+	if hook == nil {
+		delete(od.hooks, index)
+	} else {
+		od.hooks[index] = hook
+	}
+}
+
+// Add adds or replaces an object.
+func (od *ObjectDictionary) Add(object *Object) {
+	od.lock.Lock()
+	defer od.lock.Unlock()
+
+	od.objects[object.Index] = object
+}
+
+// Object returns the object at the index or nil if it does not exist.
+func (od *ObjectDictionary) Object(index uint16) *Object {
+	od.lock.RLock()
+	defer od.lock.RUnlock()
+
+	return od.objects[index]
+}
+
+// Indexes returns the indexes of all objects in ascending order.
+func (od *ObjectDictionary) Indexes() []uint16 {
+	od.lock.RLock()
+	defer od.lock.RUnlock()
+
+	indexes := make([]uint16, 0, len(od.objects))
+	for index := range od.objects {
+		indexes = append(indexes, index)
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return indexes[i] < indexes[j]
+	})
+
+	return indexes
+}
+
+// Variable returns the variable at the object index.
+func (od *ObjectDictionary) Variable(objectIndex canopen.ObjectIndex) (*Variable, canopen.SDOAbortCode) {
+	od.lock.RLock()
+	defer od.lock.RUnlock()
+
+	return od.variable(objectIndex)
+}
+
+// Read returns the value of an object as requested by an SDO upload.
+func (od *ObjectDictionary) Read(objectIndex canopen.ObjectIndex) ([]byte, canopen.SDOAbortCode) {
+	od.lock.RLock()
+	defer od.lock.RUnlock()
+
+	variable, abortCode := od.variable(objectIndex)
+	if abortCode != canopen.NO_ERROR {
+		return nil, abortCode
+	}
+
+	if !variable.AccessType.IsReadable() {
+		return nil, canopen.SDO_ERR_ACCESS_WO
+	}
+
+	return append([]byte{}, variable.value...), canopen.NO_ERROR
+}
+
+// Write sets the value of an object as requested by an SDO download.
+// The value is checked against the access type, the size of the data type and the limits of the variable.
+// Writes of the same object are executed one after another.
+func (od *ObjectDictionary) Write(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+	od.lock.RLock()
+	variable, abortCode := od.variable(objectIndex)
+	hook := od.hooks[objectIndex.Index.Index()]
+	od.lock.RUnlock()
+
+	if abortCode != canopen.NO_ERROR {
+		return abortCode
+	}
+
+	// The value must not change between the hook and storing the value
+	variable.writeLock.Lock()
+	defer variable.writeLock.Unlock()
+
+	if !variable.AccessType.IsWritable() {
+		return canopen.SDO_ERR_ACCESS_RO
+	}
+
+	od.lock.RLock()
+	abortCode = variable.check(data)
+	od.lock.RUnlock()
+
+	if abortCode != canopen.NO_ERROR {
+		return abortCode
+	}
+
+	// The hook is called without the lock, so it can update other objects
+	if hook != nil {
+		if abortCode = hook(objectIndex, data); abortCode != canopen.NO_ERROR {
+			return abortCode
+		}
+	}
+
+	od.lock.Lock()
+	defer od.lock.Unlock()
+
+	variable.value = append([]byte{}, data...)
+	return canopen.NO_ERROR
+}
+
+// Value returns the current value of an object regardless of the access type.
+func (od *ObjectDictionary) Value(objectIndex canopen.ObjectIndex) ([]byte, canopen.SDOAbortCode) {
+	od.lock.RLock()
+	defer od.lock.RUnlock()
+
+	variable, abortCode := od.variable(objectIndex)
+	if abortCode != canopen.NO_ERROR {
+		return nil, abortCode
+	}
+
+	return append([]byte{}, variable.value...), canopen.NO_ERROR
+}
+
+// SetValue sets the current value of an object regardless of the access type,
+// it is used by the application to update read only objects.
+func (od *ObjectDictionary) SetValue(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+	od.lock.Lock()
+	defer od.lock.Unlock()
+
+	variable, abortCode := od.variable(objectIndex)
+	if abortCode != canopen.NO_ERROR {
+		return abortCode
+	}
+
+	if abortCode = variable.check(data); abortCode != canopen.NO_ERROR {
+		return abortCode
+	}
+
+	variable.value = append([]byte{}, data...)
+	return canopen.NO_ERROR
+}
+
+// SetValues sets the current values of several objects like SetValue, objectIndexes and values must have the same length.
+// Either all values are set or none, if a value is rejected the position of it and the abort code are returned.
+func (od *ObjectDictionary) SetValues(objectIndexes []canopen.ObjectIndex, values [][]byte) (int, canopen.SDOAbortCode) {
+	od.lock.Lock()
+	defer od.lock.Unlock()
+
+	variables := make([]*Variable, len(objectIndexes))
+	for i, objectIndex := range objectIndexes {
+		variable, abortCode := od.variable(objectIndex)
+		if abortCode != canopen.NO_ERROR {
+			return i, abortCode
+		}
+
+		if abortCode = variable.check(values[i]); abortCode != canopen.NO_ERROR {
+			return i, abortCode
+		}
+		variables[i] = variable
+	}
+
+	for i, variable := range variables {
+		variable.value = append([]byte{}, values[i]...)
+	}
+
+	return 0, canopen.NO_ERROR
+}
+
+func (od *ObjectDictionary) variable(objectIndex canopen.ObjectIndex) (*Variable, canopen.SDOAbortCode) {
+	object, ok := od.objects[objectIndex.Index.Index()]
+	if !ok {
+		return nil, canopen.SDO_ERR_NO_OBJECT
+	}
+
+	variable := object.SubIndex(objectIndex.SubIndex)
+	if variable == nil {
+		return nil, canopen.SDO_ERR_NO_SUB_INDEX
+	}
+
+	return variable, canopen.NO_ERROR
+}
+
+// check validates the length and the limits of a new value
+func (variable *Variable) check(data []byte) canopen.SDOAbortCode {
+	if size := sdo.DataTypeSize(variable.DataType); size > 0 {
+		if len(data) > size {
+			return canopen.SDO_ERR_DATATYPE_HIGH
+		} else if len(data) < size {
+			return canopen.SDO_ERR_DATATYPE_LOW
+		}
+	}
+
+	if len(variable.HighLimit) > 0 && compareValues(variable.DataType, data, variable.HighLimit) > 0 {
+		return canopen.SDO_ERR_VALUE_HIGH
+	}
+
+	if len(variable.LowLimit) > 0 && compareValues(variable.DataType, data, variable.LowLimit) < 0 {
+		return canopen.SDO_ERR_VALUE_LOW
+	}
+
+	return canopen.NO_ERROR
+}
+
+// compareValues compares two numeric values of a data type.
+// The result is 0 if a == b, -1 if a < b, and +1 if a > b. Non numeric values are always equal.
+func compareValues(dataType sdo.SDODataType, a []byte, b []byte) int {
+	switch dataType {
+	case sdo.DATA_TYPE_BOOLEAN, sdo.DATA_TYPE_UNSIGNED_8, sdo.DATA_TYPE_UNSIGNED_16, sdo.DATA_TYPE_UNSIGNED_24, sdo.DATA_TYPE_UNSIGNED_32, sdo.DATA_TYPE_UNSIGNED_40, sdo.DATA_TYPE_UNSIGNED_48, sdo.DATA_TYPE_UNSIGNED_56, sdo.DATA_TYPE_UNSIGNED_64:
+		return compare(sdo.ParseUInt(append([]byte{}, a...)), sdo.ParseUInt(append([]byte{}, b...)))
+
+	case sdo.DATA_TYPE_INTEGER_8, sdo.DATA_TYPE_INTEGER_16, sdo.DATA_TYPE_INTEGER_24, sdo.DATA_TYPE_INTEGER_32, sdo.DATA_TYPE_INTEGER_40, sdo.DATA_TYPE_INTEGER_48, sdo.DATA_TYPE_INTEGER_56, sdo.DATA_TYPE_INTEGER_64:
+		x, _ := sdo.ParseInt(append([]byte{}, a...))
+		y, _ := sdo.ParseInt(append([]byte{}, b...))
+		return compare(x, y)
+
+	case sdo.DATA_TYPE_REAL_32:
+		if len(a) != 4 || len(b) != 4 {
+			return 0
+		}
+		return compare(math.Float32frombits(binary.LittleEndian.Uint32(a)), math.Float32frombits(binary.LittleEndian.Uint32(b)))
+
+	case sdo.DATA_TYPE_REAL_64:
+		if len(a) != 8 || len(b) != 8 {
+			return 0
+		}
+		return compare(math.Float64frombits(binary.LittleEndian.Uint64(a)), math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	}
+
+	return 0
+}
+
+func compare[T int64 | uint64 | float32 | float64](a T, b T) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,175 @@
+package od
+
+import (
+	"bytes"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"sync"
+	"testing"
+	"time"
+)
+
+func getObjectDictionary() *ObjectDictionary {
+	od := NewObjectDictionary()
+	od.Add(NewVariable(0x1000, "Device type", sdo.DATA_TYPE_UNSIGNED_32, ACCESS_TYPE_RO, []byte{0x91, 0x01, 0x0F, 0x00}))
+	od.Add(NewVariable(0x1005, "COB-ID SYNC", sdo.DATA_TYPE_UNSIGNED_32, ACCESS_TYPE_RW, []byte{0x80, 0x00, 0x00, 0x00}))
+	od.Add(NewVariable(0x1010, "Store parameters", sdo.DATA_TYPE_UNSIGNED_32, ACCESS_TYPE_WO, []byte{0x00, 0x00, 0x00, 0x00}))
+
+	limited := NewRecord(0x2000, "Limits")
+	limited.AddSubIndex(&Variable{
+		Name:         "Temperature",
+		SubIndex:     1,
+		DataType:     sdo.DATA_TYPE_INTEGER_16,
+		AccessType:   ACCESS_TYPE_RW,
+		DefaultValue: []byte{0x00, 0x00},
+		LowLimit:     []byte{0xD8, 0xFF}, // -40
+		HighLimit:    []byte{0x78, 0x00}, // 120
+	})
+	od.Add(limited)
+
+	return od
+}
+
+func TestRead(t *testing.T) {
+	od := getObjectDictionary()
+
+	data, abortCode := od.Read(canopen.NewObjectIndex(0x1000, 0))
+	if abortCode != canopen.NO_ERROR || !bytes.Equal(data, []byte{0x91, 0x01, 0x0F, 0x00}) {
+		t.Log("Unexpected value", data, abortCode)
+		t.FailNow()
+	}
+
+	for objectIndex, expected := range map[canopen.ObjectIndex]canopen.SDOAbortCode{
+		canopen.NewObjectIndex(0x1001, 0): canopen.SDO_ERR_NO_OBJECT,
+		canopen.NewObjectIndex(0x1000, 1): canopen.SDO_ERR_NO_SUB_INDEX,
+		canopen.NewObjectIndex(0x1010, 0): canopen.SDO_ERR_ACCESS_WO,
+	} {
+		if _, abortCode := od.Read(objectIndex); abortCode != expected {
+			t.Log("Unexpected abort code", objectIndex.String(), abortCode)
+			t.FailNow()
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	od := getObjectDictionary()
+
+	objectIndex := canopen.NewObjectIndex(0x1005, 0)
+	if abortCode := od.Write(objectIndex, []byte{0x81, 0x00, 0x00, 0x40}); abortCode != canopen.NO_ERROR {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if data, _ := od.Read(objectIndex); !bytes.Equal(data, []byte{0x81, 0x00, 0x00, 0x40}) {
+		t.Log("Value was not written", data)
+		t.FailNow()
+	}
+
+	temperature := canopen.NewObjectIndex(0x2000, 1)
+	for _, test := range []struct {
+		objectIndex canopen.ObjectIndex
+		data        []byte
+		expected    canopen.SDOAbortCode
+	}{
+		{canopen.NewObjectIndex(0x1000, 0), []byte{0x00, 0x00, 0x00, 0x00}, canopen.SDO_ERR_ACCESS_RO},
+		{objectIndex, []byte{0x00, 0x00, 0x00, 0x00, 0x00}, canopen.SDO_ERR_DATATYPE_HIGH},
+		{objectIndex, []byte{0x00}, canopen.SDO_ERR_DATATYPE_LOW},
+		{temperature, []byte{0x79, 0x00}, canopen.SDO_ERR_VALUE_HIGH},
+		{temperature, []byte{0xD7, 0xFF}, canopen.SDO_ERR_VALUE_LOW},
+		{temperature, []byte{0xD8, 0xFF}, canopen.NO_ERROR},
+	} {
+		if abortCode := od.Write(test.objectIndex, test.data); abortCode != test.expected {
+			t.Log("Unexpected abort code", test.objectIndex.String(), test.data, abortCode)
+			t.FailNow()
+		}
+	}
+}
+
+func TestSetValues(t *testing.T) {
+	od := getObjectDictionary()
+
+	syncCobID := canopen.NewObjectIndex(0x1005, 0)
+	temperature := canopen.NewObjectIndex(0x2000, 1)
+	objectIndexes := []canopen.ObjectIndex{syncCobID, temperature}
+
+	// The temperature exceeds the limit, so no value is set
+	if i, abortCode := od.SetValues(objectIndexes, [][]byte{{0x81, 0x00, 0x00, 0x00}, {0x79, 0x00}}); i != 1 || abortCode != canopen.SDO_ERR_VALUE_HIGH {
+		t.Log("Unexpected result", i, abortCode)
+		t.FailNow()
+	}
+
+	if data, _ := od.Value(syncCobID); !bytes.Equal(data, []byte{0x80, 0x00, 0x00, 0x00}) {
+		t.Log("Value of a rejected write was set", data)
+		t.FailNow()
+	}
+
+	if _, abortCode := od.SetValues(objectIndexes, [][]byte{{0x81, 0x00, 0x00, 0x00}, {0x78, 0x00}}); abortCode != canopen.NO_ERROR {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if data, _ := od.Value(temperature); !bytes.Equal(data, []byte{0x78, 0x00}) {
+		t.Log("Value was not set", data)
+		t.FailNow()
+	}
+}
+
+func TestWriteHook(t *testing.T) {
+	od := getObjectDictionary()
+
+	objectIndex := canopen.NewObjectIndex(0x1005, 0)
+	od.OnWrite(0x1005, func(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+		// The hook can access the object dictionary
+		if current, _ := od.Value(objectIndex); data[3]&0x40 != 0 && current[3]&0x40 != 0 {
+			return canopen.SDO_ERR_DATA_STORE_STATE
+		}
+
+		return canopen.NO_ERROR
+	})
+
+	if abortCode := od.Write(objectIndex, []byte{0x80, 0x00, 0x00, 0x40}); abortCode != canopen.NO_ERROR {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if abortCode := od.Write(objectIndex, []byte{0x81, 0x00, 0x00, 0x40}); abortCode != canopen.SDO_ERR_DATA_STORE_STATE {
+		t.Log("Unexpected abort code", abortCode)
+		t.FailNow()
+	}
+
+	if data, _ := od.Read(objectIndex); !bytes.Equal(data, []byte{0x80, 0x00, 0x00, 0x40}) {
+		t.Log("Rejected value was written", data)
+		t.FailNow()
+	}
+}
+
+func TestWriteConcurrent(t *testing.T) {
+	od := getObjectDictionary()
+
+	// The hook sees the writes in the order in which they are stored
+	var lock sync.Mutex
+	var approved []byte
+	od.OnWrite(0x1005, func(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+		lock.Lock()
+		approved = data
+		lock.Unlock()
+
+		time.Sleep(time.Millisecond)
+		return canopen.NO_ERROR
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i byte) {
+			defer wg.Done()
+			od.Write(canopen.NewObjectIndex(0x1005, 0), []byte{i, 0x00, 0x00, 0x00})
+		}(byte(i))
+	}
+	wg.Wait()
+
+	if data, _ := od.Value(canopen.NewObjectIndex(0x1005, 0)); !bytes.Equal(data, approved) {
+		t.Log("Stored value was not approved last", data, approved)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,105 @@
+package pdo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+)
+
+// Pack returns the PDO data with the current values of the mapped objects.
+// The objects are packed in order starting with the least significant bit of the first byte.
+func (mapping Mapping) Pack(dictionary *od.ObjectDictionary) ([]byte, error) {
+	var bits uint64
+	offset := 0
+	for _, object := range mapping {
+		if !object.IsDummy() {
+			value, abortCode := dictionary.Value(object.ObjectIndex())
+			if abortCode != canopen.NO_ERROR {
+				return nil, fmt.Errorf("reading mapped object %X:%d: %s", object.Index, object.SubIndex, canopen.GetAbortCodeText(abortCode))
+			}
+
+			bits |= (toUint64(value) & mask(object.Length)) << offset
+		}
+
+		offset += int(object.Length)
+	}
+
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, bits)
+
+	return data[:mapping.Size()], nil
+}
+
+// Unpack writes the values of the PDO data to the mapped objects.
+// The data must contain at least all mapped bits.
+// If a value is rejected, none of the mapped objects are written.
+func (mapping Mapping) Unpack(dictionary *od.ObjectDictionary, data []byte) error {
+	if len(data) < mapping.Size() {
+		return fmt.Errorf("%w: %d bytes received, %d bytes mapped", ErrLength, len(data), mapping.Size())
+	}
+
+	bits := toUint64(data)
+	offset := 0
+	objects := make([]MappedObject, 0, len(mapping))
+	objectIndexes := make([]canopen.ObjectIndex, 0, len(mapping))
+	values := make([][]byte, 0, len(mapping))
+	for _, object := range mapping {
+		if !object.IsDummy() {
+			variable, abortCode := dictionary.Variable(object.ObjectIndex())
+			if abortCode != canopen.NO_ERROR {
+				return fmt.Errorf("mapped object %X:%d: %s", object.Index, object.SubIndex, canopen.GetAbortCodeText(abortCode))
+			}
+
+			n := (bits >> offset) & mask(object.Length)
+
+			// Signed values are sign extended to the size of the variable
+			if sdo.IsReversed(variable.DataType) && object.Length > 0 && object.Length < 64 && n&(1<<(object.Length-1)) != 0 {
+				n |= ^mask(object.Length)
+			}
+
+			value := make([]byte, 8)
+			binary.LittleEndian.PutUint64(value, n)
+
+			objects = append(objects, object)
+			objectIndexes = append(objectIndexes, object.ObjectIndex())
+			values = append(values, value[:variableSize(variable, object)])
+		}
+
+		offset += int(object.Length)
+	}
+
+	// All values are written together, so a rejected PDO leaves the dictionary unchanged
+	if i, abortCode := dictionary.SetValues(objectIndexes, values); abortCode != canopen.NO_ERROR {
+		return fmt.Errorf("writing mapped object %X:%d: %s", objects[i].Index, objects[i].SubIndex, canopen.GetAbortCodeText(abortCode))
+	}
+
+	return nil
+}
+
+// variableSize returns the number of bytes of a mapped variable,
+// variables without fixed size (e.g. OCTET_STRING) have the mapped size
+func variableSize(variable *od.Variable, object MappedObject) int {
+	if size := sdo.DataTypeSize(variable.DataType); size > 0 {
+		return size
+	}
+
+	return (int(object.Length) + 7) / 8
+}
+
+// mask returns a mask of the lowest length bits
+func mask(length uint8) uint64 {
+	if length >= 64 {
+		return ^uint64(0)
+	}
+
+	return (1 << length) - 1
+}
+
+func toUint64(data []byte) uint64 {
+	buffer := make([]byte, 8)
+	copy(buffer, data)
+
+	return binary.LittleEndian.Uint64(buffer)
+}
@@ -0,0 +1,294 @@
+package pdo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"time"
+)
+
+const (
+	// RPDOCommunicationIndex is the index of the communication parameters of RPDO1, RPDOn is at 0x1400 + n - 1
+	RPDOCommunicationIndex uint16 = 0x1400
+	// RPDOMappingIndex is the index of the mapping parameters of RPDO1
+	RPDOMappingIndex uint16 = 0x1600
+	// TPDOCommunicationIndex is the index of the communication parameters of TPDO1
+	TPDOCommunicationIndex uint16 = 0x1800
+	// TPDOMappingIndex is the index of the mapping parameters of TPDO1
+	TPDOMappingIndex uint16 = 0x1A00
+
+	// MaxPDOs is the number of RPDOs and TPDOs a device can have
+	MaxPDOs = 512
+	// MaxMappedObjects is the number of objects which can be mapped into a PDO
+	MaxMappedObjects = 64
+	// MaxLength is the number of bits of a PDO
+	MaxLength = 64
+)
+
+// Bits of the COB-ID (sub index 1 of the communication parameters)
+const (
+	// CobIDInvalid disables the PDO
+	CobIDInvalid uint32 = 1 << 31
+	// CobIDNoRTR disallows remote transmit requests
+	CobIDNoRTR uint32 = 1 << 30
+	// CobIDExtended selects a 29-bit CAN identifier, which is not supported
+	CobIDExtended uint32 = 1 << 29
+)
+
+// Transmission types (sub index 2 of the communication parameters)
+const (
+	// TransmissionSyncAcyclic transmits on the next SYNC after an event
+	TransmissionSyncAcyclic uint8 = 0
+	// TransmissionSyncMax is the largest cyclic synchronous type, type n transmits on every n-th SYNC
+	TransmissionSyncMax uint8 = 240
+	// TransmissionRTRSync samples the data on SYNC and transmits on RTR
+	TransmissionRTRSync uint8 = 252
+	// TransmissionRTREvent samples and transmits the data on RTR
+	TransmissionRTREvent uint8 = 253
+	// TransmissionEventManufacturer transmits on a manufacturer specific event or the event timer
+	TransmissionEventManufacturer uint8 = 254
+	// TransmissionEventProfile transmits on a device profile specific event or the event timer
+	TransmissionEventProfile uint8 = 255
+)
+
+const (
+	inhibitTimeUnit = 100 * time.Microsecond
+	eventTimerUnit  = time.Millisecond
+)
+
+// CommunicationParameters are the communication parameters of a PDO (0x1400-0x15FF, 0x1800-0x19FF).
+type CommunicationParameters struct {
+	// CobID contains the 11-bit COB-ID and the CobIDInvalid and CobIDNoRTR flags (sub index 1)
+	CobID uint32
+	// TransmissionType defines when the PDO is sent or received values are applied (sub index 2)
+	TransmissionType uint8
+	// InhibitTime is the minimal time between two event driven TPDOs, in multiples of 100 µs (sub index 3)
+	InhibitTime time.Duration
+	// EventTimer is the interval of event driven TPDOs or the receive timeout of RPDOs, in ms (sub index 5)
+	EventTimer time.Duration
+	// SyncStartValue is the SYNC counter value on which a cyclic synchronous TPDO starts counting, 0 = not used (sub index 6)
+	SyncStartValue uint8
+}
+
+// DefaultCobID returns the pre-defined COB-ID of the RPDOs or TPDOs 1-4 of a node.
+func DefaultCobID(transmit bool, number int, nodeID uint8) uint32 {
+	cobID := uint32(canopen.MessageTypeRPDO1)
+	if transmit {
+		cobID = uint32(canopen.MessageTypeTPDO1)
+	}
+
+	return cobID + uint32(number-1)*0x100 + uint32(nodeID)
+}
+
+// Valid returns true if the PDO is enabled.
+func (parameters CommunicationParameters) Valid() bool {
+	return parameters.CobID&CobIDInvalid == 0
+}
+
+// CanID returns the 11-bit COB-ID of the PDO.
+func (parameters CommunicationParameters) CanID() uint16 {
+	return uint16(parameters.CobID & canopen.MaskCobID)
+}
+
+// IsSynchronous returns true if the PDO is transmitted or processed on SYNC.
+func (parameters CommunicationParameters) IsSynchronous() bool {
+	return parameters.TransmissionType <= TransmissionSyncMax
+}
+
+// IsEventDriven returns true if the PDO is transmitted on events and the event timer.
+func (parameters CommunicationParameters) IsEventDriven() bool {
+	return parameters.TransmissionType >= TransmissionEventManufacturer
+}
+
+// Validate checks the parameters for values which are not supported.
+func (parameters CommunicationParameters) Validate() error {
+	if parameters.CobID&CobIDExtended != 0 {
+		return fmt.Errorf("extended COB-ID %X is not supported", parameters.CobID)
+	}
+
+	if parameters.TransmissionType > TransmissionSyncMax && parameters.TransmissionType < TransmissionRTRSync {
+		return fmt.Errorf("reserved transmission type %d", parameters.TransmissionType)
+	}
+
+	return nil
+}
+
+// Values returns the encoded values of the sub indexes 1, 2, 3, 5 and 6.
+func (parameters CommunicationParameters) Values() map[uint8][]byte {
+	cobID := make([]byte, 4)
+	binary.LittleEndian.PutUint32(cobID, parameters.CobID)
+
+	inhibitTime := make([]byte, 2)
+	binary.LittleEndian.PutUint16(inhibitTime, uint16(parameters.InhibitTime/inhibitTimeUnit))
+
+	eventTimer := make([]byte, 2)
+	binary.LittleEndian.PutUint16(eventTimer, uint16(parameters.EventTimer/eventTimerUnit))
+
+	return map[uint8][]byte{
+		1: cobID,
+		2: {parameters.TransmissionType},
+		3: inhibitTime,
+		5: eventTimer,
+		6: {parameters.SyncStartValue},
+	}
+}
+
+// SetValue decodes the value of a sub index, unknown sub indexes are ignored.
+func (parameters *CommunicationParameters) SetValue(subIndex uint8, data []byte) error {
+	sizes := map[uint8]int{1: 4, 2: 1, 3: 2, 5: 2, 6: 1}
+	size, ok := sizes[subIndex]
+	if !ok {
+		return nil
+	}
+
+	if len(data) < size {
+		return fmt.Errorf("invalid length %d of sub index %d", len(data), subIndex)
+	}
+
+	switch subIndex {
+	case 1:
+		parameters.CobID = binary.LittleEndian.Uint32(data)
+	case 2:
+		parameters.TransmissionType = data[0]
+	case 3:
+		parameters.InhibitTime = time.Duration(binary.LittleEndian.Uint16(data)) * inhibitTimeUnit
+	case 5:
+		parameters.EventTimer = time.Duration(binary.LittleEndian.Uint16(data)) * eventTimerUnit
+	case 6:
+		parameters.SyncStartValue = data[0]
+	}
+
+	return nil
+}
+
+// ReadCommunicationParameters reads the communication parameters at the index from an object dictionary.
+// Optional sub indexes which don't exist keep their zero value.
+func ReadCommunicationParameters(dictionary *od.ObjectDictionary, index uint16) (CommunicationParameters, error) {
+	parameters := CommunicationParameters{}
+	object := dictionary.Object(index)
+	if object == nil {
+		return parameters, fmt.Errorf("object %X does not exist", index)
+	}
+
+	for _, subIndex := range []uint8{1, 2, 3, 5, 6} {
+		data, abortCode := dictionary.Value(canopen.NewObjectIndex(index, subIndex))
+		if abortCode == canopen.SDO_ERR_NO_SUB_INDEX && subIndex > 2 {
+			continue
+		} else if abortCode != canopen.NO_ERROR {
+			return parameters, fmt.Errorf("reading %X:%d: %s", index, subIndex, canopen.GetAbortCodeText(abortCode))
+		}
+
+		if err := parameters.SetValue(subIndex, data); err != nil {
+			return parameters, err
+		}
+	}
+
+	return parameters, nil
+}
+
+// MappedObject is an entry of the mapping parameters.
+type MappedObject struct {
+	Index    uint16
+	SubIndex uint8
+	// Length is the number of mapped bits
+	Length uint8
+}
+
+// ParseMappedObject decodes a mapping entry (bits 16-31 index, 8-15 sub index, 0-7 length in bits).
+func ParseMappedObject(entry uint32) MappedObject {
+	return MappedObject{
+		Index:    uint16(entry >> 16),
+		SubIndex: uint8(entry >> 8),
+		Length:   uint8(entry),
+	}
+}
+
+// Entry returns the encoded mapping entry.
+func (object MappedObject) Entry() uint32 {
+	return uint32(object.Index)<<16 | uint32(object.SubIndex)<<8 | uint32(object.Length)
+}
+
+// ObjectIndex returns the object index of the mapped object.
+func (object MappedObject) ObjectIndex() canopen.ObjectIndex {
+	return canopen.NewObjectIndex(object.Index, object.SubIndex)
+}
+
+// IsDummy returns true for dummy entries which map the data types 0x0001-0x001F to skip bits.
+func (object MappedObject) IsDummy() bool {
+	return object.Index < 0x20
+}
+
+// Mapping contains the objects mapped into a PDO in the order of transmission.
+type Mapping []MappedObject
+
+// Length returns the number of mapped bits.
+func (mapping Mapping) Length() int {
+	length := 0
+	for _, object := range mapping {
+		length += int(object.Length)
+	}
+
+	return length
+}
+
+// Size returns the number of bytes of the PDO.
+func (mapping Mapping) Size() int {
+	return (mapping.Length() + 7) / 8
+}
+
+// Validate checks that the mapped objects exist, can be mapped and fit into a PDO.
+func (mapping Mapping) Validate(dictionary *od.ObjectDictionary) error {
+	if len(mapping) > MaxMappedObjects {
+		return fmt.Errorf("%d mapped objects exceed the maximum of %d", len(mapping), MaxMappedObjects)
+	}
+
+	if length := mapping.Length(); length > MaxLength {
+		return fmt.Errorf("mapped length of %d bits exceeds the maximum of %d bits", length, MaxLength)
+	}
+
+	for _, object := range mapping {
+		if object.Length == 0 {
+			return fmt.Errorf("mapped object %X:%d has no length", object.Index, object.SubIndex)
+		}
+
+		if object.IsDummy() {
+			continue
+		}
+
+		variable, abortCode := dictionary.Variable(object.ObjectIndex())
+		if abortCode != canopen.NO_ERROR {
+			return fmt.Errorf("mapped object %X:%d: %s", object.Index, object.SubIndex, canopen.GetAbortCodeText(abortCode))
+		}
+
+		if !variable.PDOMapping {
+			return fmt.Errorf("object %X:%d can not be mapped into a PDO", object.Index, object.SubIndex)
+		}
+
+		if size := variableSize(variable, object); int(object.Length) > size*8 {
+			return fmt.Errorf("mapped length %d of %X:%d exceeds the size of the object", object.Length, object.Index, object.SubIndex)
+		}
+	}
+
+	return nil
+}
+
+// ReadMapping reads the mapping parameters at the index from an object dictionary.
+func ReadMapping(dictionary *od.ObjectDictionary, index uint16) (Mapping, error) {
+	count, abortCode := dictionary.Value(canopen.NewObjectIndex(index, 0))
+	if abortCode != canopen.NO_ERROR || len(count) != 1 {
+		return nil, fmt.Errorf("reading %X:0: %s", index, canopen.GetAbortCodeText(abortCode))
+	}
+
+	mapping := Mapping{}
+	for subIndex := uint8(1); subIndex <= count[0]; subIndex++ {
+		data, abortCode := dictionary.Value(canopen.NewObjectIndex(index, subIndex))
+		if abortCode != canopen.NO_ERROR || len(data) != 4 {
+			return nil, fmt.Errorf("reading %X:%d: %s", index, subIndex, canopen.GetAbortCodeText(abortCode))
+		}
+
+		mapping = append(mapping, ParseMappedObject(binary.LittleEndian.Uint32(data)))
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,265 @@
+package pdo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"github.com/FabianPetersen/canopen/syncobj"
+	"net"
+	"testing"
+	"time"
+)
+
+func getObjectDictionary() *od.ObjectDictionary {
+	dictionary := od.NewObjectDictionary()
+	values := od.NewRecord(0x2000, "Values")
+	for _, variable := range []*od.Variable{
+		{Name: "Enabled", SubIndex: 1, DataType: sdo.DATA_TYPE_BOOLEAN, DefaultValue: []byte{1}},
+		{Name: "Mode", SubIndex: 2, DataType: sdo.DATA_TYPE_UNSIGNED_8, DefaultValue: []byte{0x05}},
+		{Name: "Speed", SubIndex: 3, DataType: sdo.DATA_TYPE_INTEGER_16, DefaultValue: []byte{0x34, 0x12}},
+		{Name: "Position", SubIndex: 4, DataType: sdo.DATA_TYPE_UNSIGNED_32, DefaultValue: []byte{0x78, 0x56, 0x34, 0x12}},
+	} {
+		variable.AccessType = od.ACCESS_TYPE_RW
+		variable.PDOMapping = true
+		values.AddSubIndex(variable)
+	}
+	dictionary.Add(values)
+	dictionary.Add(od.NewVariable(0x2001, "Not mappable", sdo.DATA_TYPE_UNSIGNED_8, od.ACCESS_TYPE_RW, []byte{0}))
+
+	return dictionary
+}
+
+// mapping maps 1 bit, 3 dummy bits, 4 bits of the mode, 16 bits and 32 bits
+var mapping = Mapping{
+	{Index: 0x2000, SubIndex: 1, Length: 1},
+	{Index: 0x0001, SubIndex: 0, Length: 3},
+	{Index: 0x2000, SubIndex: 2, Length: 4},
+	{Index: 0x2000, SubIndex: 3, Length: 16},
+	{Index: 0x2000, SubIndex: 4, Length: 32},
+}
+
+func TestPack(t *testing.T) {
+	dictionary := getObjectDictionary()
+	if err := mapping.Validate(dictionary); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	data, err := mapping.Pack(dictionary)
+	expected := []byte{0x51, 0x34, 0x12, 0x78, 0x56, 0x34, 0x12}
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Log("Unexpected data", data, err, "expected", expected)
+		t.FailNow()
+	}
+
+	if err := mapping.Unpack(dictionary, []byte{0xA0, 0xCD, 0xAB, 0x01, 0x02, 0x03, 0x04}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for subIndex, expected := range map[uint8][]byte{1: {0}, 2: {0x0A}, 3: {0xCD, 0xAB}, 4: {0x01, 0x02, 0x03, 0x04}} {
+		if value, _ := dictionary.Value(canopen.NewObjectIndex(0x2000, subIndex)); !bytes.Equal(value, expected) {
+			t.Log("Unexpected value of sub index", subIndex, value)
+			t.FailNow()
+		}
+	}
+
+	if err := mapping.Unpack(dictionary, []byte{0x00}); err == nil {
+		t.Log("Expected length error")
+		t.FailNow()
+	}
+
+	for _, invalid := range []Mapping{
+		{{Index: 0x2001, SubIndex: 0, Length: 8}},
+		{{Index: 0x2000, SubIndex: 2, Length: 9}},
+		{{Index: 0x2000, SubIndex: 5, Length: 8}},
+		{{Index: 0x2000, SubIndex: 4, Length: 32}, {Index: 0x2000, SubIndex: 4, Length: 32}, {Index: 0x2000, SubIndex: 1, Length: 1}},
+	} {
+		if err := invalid.Validate(dictionary); err == nil {
+			t.Log("Expected invalid mapping", invalid)
+			t.FailNow()
+		}
+	}
+}
+
+func TestUnpackSigned(t *testing.T) {
+	dictionary := getObjectDictionary()
+	speed := canopen.NewObjectIndex(0x2000, 3)
+
+	// Signed objects mapped with less bits than their size are sign extended
+	signed := Mapping{{Index: 0x2000, SubIndex: 3, Length: 12}, {Index: 0x2000, SubIndex: 2, Length: 4}}
+	for _, test := range []struct {
+		data     []byte
+		expected []byte
+	}{
+		{[]byte{0xFE, 0xFF}, []byte{0xFE, 0xFF}},
+		{[]byte{0x00, 0x08}, []byte{0x00, 0xF8}},
+		{[]byte{0xFF, 0x07}, []byte{0xFF, 0x07}},
+	} {
+		if err := signed.Unpack(dictionary, test.data); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if value, _ := dictionary.Value(speed); !bytes.Equal(value, test.expected) {
+			t.Log("Unexpected value", test.data, value)
+			t.FailNow()
+		}
+	}
+
+	// Unsigned objects are not sign extended
+	if err := signed.Unpack(dictionary, []byte{0x00, 0xF0}); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if value, _ := dictionary.Value(canopen.NewObjectIndex(0x2000, 2)); !bytes.Equal(value, []byte{0x0F}) {
+		t.Log("Unexpected unsigned value", value)
+		t.FailNow()
+	}
+}
+
+func TestUnpackRejected(t *testing.T) {
+	dictionary := getObjectDictionary()
+	dictionary.Object(0x2000).SubIndex(4).HighLimit = []byte{0xFF, 0xFF, 0x00, 0x00}
+
+	// The position after the speed exceeds the limit, so the speed is not written either
+	if err := mapping.Unpack(dictionary, []byte{0xA0, 0xCD, 0xAB, 0x01, 0x02, 0x03, 0x04}); err == nil {
+		t.Log("Expected limit error")
+		t.FailNow()
+	}
+
+	for subIndex, expected := range map[uint8][]byte{1: {1}, 2: {0x05}, 3: {0x34, 0x12}, 4: {0x78, 0x56, 0x34, 0x12}} {
+		if value, _ := dictionary.Value(canopen.NewObjectIndex(0x2000, subIndex)); !bytes.Equal(value, expected) {
+			t.Log("Rejected PDO changed sub index", subIndex, value)
+			t.FailNow()
+		}
+	}
+}
+
+func TestLoadTPDO(t *testing.T) {
+	dictionary := getObjectDictionary()
+
+	communication := od.NewRecord(0x1800, "TPDO communication parameter")
+	parameters := CommunicationParameters{CobID: 0x185, TransmissionType: 254, InhibitTime: 10 * time.Millisecond, EventTimer: time.Second}
+	for subIndex, value := range parameters.Values() {
+		communication.AddSubIndex(&od.Variable{SubIndex: subIndex, DataType: sdo.DATA_TYPE_UNSIGNED_32, AccessType: od.ACCESS_TYPE_RW, DefaultValue: value})
+	}
+	dictionary.Add(communication)
+
+	mappingObject := od.NewArray(0x1A00, "TPDO mapping parameter", sdo.DATA_TYPE_UNSIGNED_32, od.ACCESS_TYPE_RW, 8)
+	dictionary.Add(mappingObject)
+	dictionary.SetValue(canopen.NewObjectIndex(0x1A00, 0), []byte{2})
+	for i, object := range mapping[3:] {
+		entry := make([]byte, 4)
+		binary.LittleEndian.PutUint32(entry, object.Entry())
+		dictionary.SetValue(canopen.NewObjectIndex(0x1A00, uint8(i+1)), entry)
+	}
+
+	tpdo, err := LoadTPDO(dictionary, 1)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if tpdo.Communication() != parameters || len(tpdo.Mapping()) != 2 || tpdo.Mapping()[1] != mapping[4] {
+		t.Log("Unexpected TPDO", tpdo.Communication(), tpdo.Mapping())
+		t.FailNow()
+	}
+}
+
+func TestTPDO(t *testing.T) {
+	a, b := net.Pipe()
+	producerBus := can.NewBus(can.NewReadWriteCloser(a), "producer")
+	consumerBus := can.NewBus(can.NewReadWriteCloser(b), "consumer")
+	go producerBus.ConnectAndPublish()
+	go consumerBus.ConnectAndPublish()
+	defer producerBus.Disconnect()
+
+	sub := canopen.Subscribe(consumerBus, 0x185, 10)
+	defer sub.Close()
+
+	dictionary := getObjectDictionary()
+	tpdo, err := NewTPDO(dictionary, CommunicationParameters{CobID: 0x185, TransmissionType: 2, SyncStartValue: 3}, mapping[3:])
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	tpdo.Start(producerBus)
+	defer tpdo.Stop()
+
+	// Every second SYNC starting with counter 3 is answered
+	for counter := uint8(1); counter <= 6; counter++ {
+		tpdo.OnSync(syncobj.Event{Type: syncobj.EventSync, Counter: counter, HasCounter: true})
+	}
+
+	for i := 0; i < 2; i++ {
+		frame, err := sub.Receive(time.Second)
+		if err != nil || !bytes.Equal(frame.Data[:6], []byte{0x34, 0x12, 0x78, 0x56, 0x34, 0x12}) {
+			t.Log("Unexpected TPDO", frame.Data, err)
+			t.FailNow()
+		}
+	}
+
+	if frame, err := sub.Receive(50 * time.Millisecond); err == nil {
+		t.Log("Unexpected TPDO", frame.Data)
+		t.FailNow()
+	}
+
+	// RTR requests are answered
+	consumerBus.Publish(canopen.Frame{CobID: 0x185, Rtr: true, Data: make([]byte, 6)}.CANFrame())
+	if _, err := sub.Receive(time.Second); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestRPDO(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	dictionary := getObjectDictionary()
+
+	errs := make(chan error, 10)
+	received := make(chan struct{}, 10)
+	rpdo, err := NewRPDO(dictionary, CommunicationParameters{CobID: 0x205, TransmissionType: 0, EventTimer: 50 * time.Millisecond}, mapping[3:4])
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	rpdo.OnError = func(err error) { errs <- err }
+	rpdo.OnReceive = func() { received <- struct{}{} }
+	rpdo.Start(bus)
+	defer rpdo.Stop()
+
+	speed := canopen.NewObjectIndex(0x2000, 3)
+	bus.PublishLocal(canopen.NewFrame(0x205, []byte{0x01, 0x02}).CANFrame())
+	if value, _ := dictionary.Value(speed); !bytes.Equal(value, []byte{0x34, 0x12}) {
+		t.Log("Synchronous RPDO applied before SYNC", value)
+		t.FailNow()
+	}
+
+	rpdo.OnSync(syncobj.Event{Type: syncobj.EventSync})
+	<-received
+	if value, _ := dictionary.Value(speed); !bytes.Equal(value, []byte{0x01, 0x02}) {
+		t.Log("Synchronous RPDO not applied on SYNC", value)
+		t.FailNow()
+	}
+
+	bus.PublishLocal(canopen.NewFrame(0x205, []byte{0x01}).CANFrame())
+	rpdo.OnSync(syncobj.Event{Type: syncobj.EventSync})
+	for _, expected := range []error{ErrLength, ErrTimeout} {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, expected) {
+				t.Log("Unexpected error", err)
+				t.FailNow()
+			}
+		case <-time.After(time.Second):
+			t.Log("Missing error", expected)
+			t.FailNow()
+		}
+	}
+}
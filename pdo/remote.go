@@ -0,0 +1,265 @@
+package pdo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo/sdoClient"
+)
+
+// Configuration describes a PDO of a remote node.
+type Configuration struct {
+	// Transmit selects a TPDO (0x1800, 0x1A00) of the node, otherwise an RPDO (0x1400, 0x1600)
+	Transmit bool
+	// Number is the number of the PDO (1-512)
+	Number        int
+	Communication CommunicationParameters
+	Mapping       Mapping
+}
+
+// ConfigurationError reports the step of the configuration which failed.
+type ConfigurationError struct {
+	Step        string
+	ObjectIndex canopen.ObjectIndex
+	// AbortCode is set if the node aborted the SDO transfer
+	AbortCode canopen.SDOAbortCode
+	Err       error
+}
+
+func (e ConfigurationError) Error() string {
+	return fmt.Sprintf("%s (%s): %s", e.Step, e.ObjectIndex.String(), e.Err)
+}
+
+func (e ConfigurationError) Unwrap() error {
+	return e.Err
+}
+
+func (configuration Configuration) communicationIndex() uint16 {
+	if configuration.Transmit {
+		return TPDOCommunicationIndex + uint16(configuration.Number-1)
+	}
+
+	return RPDOCommunicationIndex + uint16(configuration.Number-1)
+}
+
+func (configuration Configuration) mappingIndex() uint16 {
+	if configuration.Transmit {
+		return TPDOMappingIndex + uint16(configuration.Number-1)
+	}
+
+	return RPDOMappingIndex + uint16(configuration.Number-1)
+}
+
+// remote reads and writes the objects of a node
+type remote struct {
+	client *sdoClient.Client
+}
+
+func (r remote) download(step string, objectIndex canopen.ObjectIndex, data []byte) error {
+	return configurationError(step, objectIndex, r.client.Write(objectIndex, data))
+}
+
+// upload reads an object, which must have at least size bytes
+func (r remote) upload(step string, objectIndex canopen.ObjectIndex, size int) ([]byte, error) {
+	data, err := r.client.Read(objectIndex)
+	if err != nil {
+		return nil, configurationError(step, objectIndex, err)
+	}
+
+	if len(data) < size {
+		return nil, ConfigurationError{Step: step, ObjectIndex: objectIndex, Err: fmt.Errorf("unexpected length %d (expected %d)", len(data), size)}
+	}
+
+	return data, nil
+}
+
+func configurationError(step string, objectIndex canopen.ObjectIndex, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	configurationErr := ConfigurationError{Step: step, ObjectIndex: objectIndex, Err: err}
+	var abort canopen.TransferAbort
+	if errors.As(err, &abort) {
+		configurationErr.AbortCode = abort.Code()
+	}
+
+	return configurationErr
+}
+
+// Configure applies the configuration to the PDO of a remote node in the order required by CiA 301:
+// the PDO is disabled, the communication parameters are written, the mapping is disabled, the mapping entries are written,
+// the mapping and the PDO are enabled again. Afterwards the configuration is read back for verification.
+// The inhibit time, event timer and SYNC start value are always written, nodes which don't support them must keep them 0.
+func Configure(bus *can.Bus, nodeID uint8, configuration Configuration) error {
+	if configuration.Number < 1 || configuration.Number > MaxPDOs {
+		return fmt.Errorf("invalid PDO number %d", configuration.Number)
+	}
+
+	if err := configuration.Communication.Validate(); err != nil {
+		return err
+	}
+
+	if len(configuration.Mapping) > MaxMappedObjects || configuration.Mapping.Length() > MaxLength {
+		return fmt.Errorf("mapping with %d objects and %d bits exceeds a PDO", len(configuration.Mapping), configuration.Mapping.Length())
+	}
+
+	communication := configuration.Communication
+	if communication.CobID&canopen.MaskCobID == 0 && configuration.Number <= 4 {
+		communication.CobID |= DefaultCobID(configuration.Transmit, configuration.Number, nodeID)
+	}
+
+	r := remote{client: sdoClient.NewClient(bus, nodeID)}
+	communicationIndex := configuration.communicationIndex()
+	mappingIndex := configuration.mappingIndex()
+	values := communication.Values()
+
+	// Disable the PDO with its current COB-ID
+	cobIDIndex := canopen.NewObjectIndex(communicationIndex, 1)
+	current, err := r.upload("read COB-ID", cobIDIndex, 4)
+	if err != nil {
+		return err
+	}
+
+	disabled := make([]byte, 4)
+	binary.LittleEndian.PutUint32(disabled, binary.LittleEndian.Uint32(current)|CobIDInvalid)
+	if err := r.download("disable PDO", cobIDIndex, disabled); err != nil {
+		return err
+	}
+
+	if err := r.download("write transmission type", canopen.NewObjectIndex(communicationIndex, 2), values[2]); err != nil {
+		return err
+	}
+
+	// Optional parameters which the node doesn't support can only keep their zero value
+	for _, parameter := range []struct {
+		step     string
+		subIndex uint8
+		isZero   bool
+	}{
+		{"write inhibit time", 3, communication.InhibitTime == 0},
+		{"write event timer", 5, communication.EventTimer == 0},
+		{"write SYNC start value", 6, communication.SyncStartValue == 0},
+	} {
+		err := r.download(parameter.step, canopen.NewObjectIndex(communicationIndex, parameter.subIndex), values[parameter.subIndex])
+
+		var configurationErr ConfigurationError
+		if parameter.isZero && errors.As(err, &configurationErr) && isMissing(configurationErr.AbortCode) {
+			continue
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// The mapping can only be changed while it is disabled
+	if err := r.download("disable mapping", canopen.NewObjectIndex(mappingIndex, 0), []byte{0}); err != nil {
+		return err
+	}
+
+	for i, object := range configuration.Mapping {
+		entry := make([]byte, 4)
+		binary.LittleEndian.PutUint32(entry, object.Entry())
+		if err := r.download("write mapping entry", canopen.NewObjectIndex(mappingIndex, uint8(i+1)), entry); err != nil {
+			return err
+		}
+	}
+
+	if err := r.download("enable mapping", canopen.NewObjectIndex(mappingIndex, 0), []byte{uint8(len(configuration.Mapping))}); err != nil {
+		return err
+	}
+
+	// The PDO stays disabled if the configured COB-ID is invalid
+	if err := r.download("enable PDO", cobIDIndex, values[1]); err != nil {
+		return err
+	}
+
+	return verify(r, configuration, communication)
+}
+
+// verify reads the configuration back and compares it with the written values
+func verify(r remote, configuration Configuration, communication CommunicationParameters) error {
+	communicationIndex := configuration.communicationIndex()
+	mappingIndex := configuration.mappingIndex()
+	values := communication.Values()
+
+	expected := []struct {
+		objectIndex canopen.ObjectIndex
+		data        []byte
+	}{
+		{canopen.NewObjectIndex(communicationIndex, 1), values[1]},
+		{canopen.NewObjectIndex(communicationIndex, 2), values[2]},
+		{canopen.NewObjectIndex(mappingIndex, 0), []byte{uint8(len(configuration.Mapping))}},
+	}
+
+	for i, object := range configuration.Mapping {
+		entry := make([]byte, 4)
+		binary.LittleEndian.PutUint32(entry, object.Entry())
+		expected = append(expected, struct {
+			objectIndex canopen.ObjectIndex
+			data        []byte
+		}{canopen.NewObjectIndex(mappingIndex, uint8(i+1)), entry})
+	}
+
+	for _, e := range expected {
+		data, err := r.upload("verify", e.objectIndex, len(e.data))
+		if err != nil {
+			return err
+		}
+
+		// Expedited transfers without size indication contain 4 bytes
+		if !bytes.Equal(data[:len(e.data)], e.data) {
+			return ConfigurationError{Step: "verify", ObjectIndex: e.objectIndex, Err: fmt.Errorf("read % X, expected % X", data, e.data)}
+		}
+	}
+
+	return nil
+}
+
+// ReadConfiguration reads the configuration of a PDO from a remote node.
+// Optional communication parameters which the node doesn't support keep their zero value.
+func ReadConfiguration(bus *can.Bus, nodeID uint8, transmit bool, number int) (Configuration, error) {
+	configuration := Configuration{Transmit: transmit, Number: number}
+	r := remote{client: sdoClient.NewClient(bus, nodeID)}
+
+	communicationIndex := configuration.communicationIndex()
+	for _, subIndex := range []uint8{1, 2, 3, 5, 6} {
+		objectIndex := canopen.NewObjectIndex(communicationIndex, subIndex)
+		data, err := r.upload("read communication parameter", objectIndex, 1)
+
+		var configurationErr ConfigurationError
+		if errors.As(err, &configurationErr) && isMissing(configurationErr.AbortCode) && subIndex > 2 {
+			continue
+		} else if err != nil {
+			return configuration, err
+		}
+
+		if err := configuration.Communication.SetValue(subIndex, data); err != nil {
+			return configuration, ConfigurationError{Step: "read communication parameter", ObjectIndex: objectIndex, Err: err}
+		}
+	}
+
+	mappingIndex := configuration.mappingIndex()
+	count, err := r.upload("read mapping", canopen.NewObjectIndex(mappingIndex, 0), 1)
+	if err != nil {
+		return configuration, err
+	}
+
+	for subIndex := uint8(1); subIndex <= count[0]; subIndex++ {
+		entry, err := r.upload("read mapping entry", canopen.NewObjectIndex(mappingIndex, subIndex), 4)
+		if err != nil {
+			return configuration, err
+		}
+
+		configuration.Mapping = append(configuration.Mapping, ParseMappedObject(binary.LittleEndian.Uint32(entry)))
+	}
+
+	return configuration, nil
+}
+
+// isMissing checks if an abort code indicates that an optional parameter doesn't exist
+func isMissing(abortCode canopen.SDOAbortCode) bool {
+	return abortCode == canopen.SDO_ERR_NO_OBJECT || abortCode == canopen.SDO_ERR_NO_SUB_INDEX
+}
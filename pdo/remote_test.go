@@ -0,0 +1,125 @@
+package pdo
+
+import (
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"github.com/FabianPetersen/canopen/sdo/sdoServer"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConfigure(t *testing.T) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	defer clientBus.Disconnect()
+
+	// The remote node has no event timer
+	dictionary := od.NewObjectDictionary()
+	communication := od.NewRecord(0x1800, "TPDO communication parameter")
+	for subIndex, dataType := range map[uint8]sdo.SDODataType{1: sdo.DATA_TYPE_UNSIGNED_32, 2: sdo.DATA_TYPE_UNSIGNED_8, 3: sdo.DATA_TYPE_UNSIGNED_16, 6: sdo.DATA_TYPE_UNSIGNED_8} {
+		communication.AddSubIndex(&od.Variable{SubIndex: subIndex, DataType: dataType, AccessType: od.ACCESS_TYPE_RW, DefaultValue: make([]byte, sdo.DataTypeSize(dataType))})
+	}
+	dictionary.Add(communication)
+	dictionary.SetValue(canopen.NewObjectIndex(0x1800, 1), []byte{0x85, 0x01, 0x00, 0x00})
+
+	mappingObject := od.NewArray(0x1A00, "TPDO mapping parameter", sdo.DATA_TYPE_UNSIGNED_32, od.ACCESS_TYPE_RW, 8)
+	mappingObject.AddSubIndex(&od.Variable{SubIndex: 0, DataType: sdo.DATA_TYPE_UNSIGNED_8, AccessType: od.ACCESS_TYPE_RW, DefaultValue: []byte{0}})
+	dictionary.Add(mappingObject)
+
+	server := &sdoServer.Server{NodeId: 5, ObjectDictionary: dictionary}
+	go server.Listen(serverBus)
+	defer serverBus.Disconnect()
+	time.Sleep(10 * time.Millisecond)
+
+	configuration := Configuration{
+		Transmit: true,
+		Number:   1,
+		Communication: CommunicationParameters{
+			TransmissionType: 1,
+			InhibitTime:      time.Millisecond,
+			SyncStartValue:   2,
+		},
+		Mapping: mapping,
+	}
+	if err := Configure(clientBus, 5, configuration); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	read, err := ReadConfiguration(clientBus, 5, true, 1)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	configuration.Communication.CobID = 0x185
+	if read.Communication != configuration.Communication || len(read.Mapping) != len(mapping) || read.Mapping[4] != mapping[4] {
+		t.Log("Unexpected configuration", read)
+		t.FailNow()
+	}
+
+	configuration.Communication.EventTimer = 100 * time.Millisecond
+	err = Configure(clientBus, 5, configuration)
+
+	var configurationErr ConfigurationError
+	if !errors.As(err, &configurationErr) || configurationErr.Step != "write event timer" || configurationErr.AbortCode != canopen.SDO_ERR_NO_SUB_INDEX {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
+
+func TestConfigureReset(t *testing.T) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	defer clientBus.Disconnect()
+
+	// The remote node has an event timer of 100ms and an inhibit time of 1ms
+	dictionary := od.NewObjectDictionary()
+	communication := od.NewRecord(0x1800, "TPDO communication parameter")
+	for subIndex, dataType := range map[uint8]sdo.SDODataType{1: sdo.DATA_TYPE_UNSIGNED_32, 2: sdo.DATA_TYPE_UNSIGNED_8, 3: sdo.DATA_TYPE_UNSIGNED_16, 5: sdo.DATA_TYPE_UNSIGNED_16, 6: sdo.DATA_TYPE_UNSIGNED_8} {
+		communication.AddSubIndex(&od.Variable{SubIndex: subIndex, DataType: dataType, AccessType: od.ACCESS_TYPE_RW, DefaultValue: make([]byte, sdo.DataTypeSize(dataType))})
+	}
+	dictionary.Add(communication)
+	dictionary.SetValue(canopen.NewObjectIndex(0x1800, 1), []byte{0x85, 0x01, 0x00, 0x00})
+	dictionary.SetValue(canopen.NewObjectIndex(0x1800, 3), []byte{0x0A, 0x00})
+	dictionary.SetValue(canopen.NewObjectIndex(0x1800, 5), []byte{0x64, 0x00})
+
+	mappingObject := od.NewArray(0x1A00, "TPDO mapping parameter", sdo.DATA_TYPE_UNSIGNED_32, od.ACCESS_TYPE_RW, 8)
+	mappingObject.AddSubIndex(&od.Variable{SubIndex: 0, DataType: sdo.DATA_TYPE_UNSIGNED_8, AccessType: od.ACCESS_TYPE_RW, DefaultValue: []byte{0}})
+	dictionary.Add(mappingObject)
+
+	server := &sdoServer.Server{NodeId: 5, ObjectDictionary: dictionary}
+	go server.Listen(serverBus)
+	defer serverBus.Disconnect()
+	time.Sleep(10 * time.Millisecond)
+
+	configuration := Configuration{
+		Transmit:      true,
+		Number:        1,
+		Communication: CommunicationParameters{TransmissionType: 254},
+		Mapping:       mapping[3:],
+	}
+	if err := Configure(clientBus, 5, configuration); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	read, err := ReadConfiguration(clientBus, 5, true, 1)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if read.Communication.EventTimer != 0 || read.Communication.InhibitTime != 0 {
+		t.Log("Parameters not reset", read.Communication)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,199 @@
+package pdo
+
+import (
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/syncobj"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLength is reported if a received PDO is shorter than the mapped objects
+	ErrLength = errors.New("PDO not processed due to length error")
+	// ErrTimeout is reported if no RPDO was received within the event timer
+	ErrTimeout = errors.New("RPDO timeout")
+)
+
+// RPDO writes the received values to the mapped objects.
+// Synchronous RPDOs are applied on the next SYNC, event driven RPDOs immediately.
+type RPDO struct {
+	// OnReceive is called after the received values were written (optional)
+	OnReceive func()
+	// OnError is called if a received PDO can't be processed or the event timer expired (optional)
+	OnError func(error)
+
+	communication CommunicationParameters
+	mapping       Mapping
+	dictionary    *od.ObjectDictionary
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock     sync.Mutex
+	running  bool
+	buffered []byte
+	timer    *time.Timer
+}
+
+// NewRPDO returns an RPDO which writes to the mapped objects of the object dictionary.
+func NewRPDO(dictionary *od.ObjectDictionary, communication CommunicationParameters, mapping Mapping) (*RPDO, error) {
+	if err := communication.Validate(); err != nil {
+		return nil, err
+	}
+
+	if communication.TransmissionType > TransmissionSyncMax && !communication.IsEventDriven() {
+		return nil, fmt.Errorf("transmission type %d is not supported by RPDOs", communication.TransmissionType)
+	}
+
+	if err := mapping.Validate(dictionary); err != nil {
+		return nil, err
+	}
+
+	return &RPDO{
+		communication: communication,
+		mapping:       mapping,
+		dictionary:    dictionary,
+	}, nil
+}
+
+// LoadRPDO returns the RPDO with the number (1-512) as configured in the object dictionary (0x1400+, 0x1600+).
+func LoadRPDO(dictionary *od.ObjectDictionary, number int) (*RPDO, error) {
+	communication, err := ReadCommunicationParameters(dictionary, RPDOCommunicationIndex+uint16(number-1))
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := ReadMapping(dictionary, RPDOMappingIndex+uint16(number-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewRPDO(dictionary, communication, mapping)
+}
+
+// Communication returns the communication parameters.
+func (rpdo *RPDO) Communication() CommunicationParameters {
+	return rpdo.communication
+}
+
+// Mapping returns the mapped objects.
+func (rpdo *RPDO) Mapping() Mapping {
+	return rpdo.mapping
+}
+
+// Start enables the RPDO.
+func (rpdo *RPDO) Start(bus *can.Bus) {
+	rpdo.lock.Lock()
+	defer rpdo.lock.Unlock()
+
+	if rpdo.running || !rpdo.communication.Valid() {
+		return
+	}
+
+	rpdo.bus = bus
+	rpdo.running = true
+	rpdo.buffered = nil
+
+	rpdo.handler = can.NewHandler(rpdo.handle)
+	bus.Subscribe(rpdo.handler)
+}
+
+// Stop disables the RPDO.
+func (rpdo *RPDO) Stop() {
+	rpdo.lock.Lock()
+	defer rpdo.lock.Unlock()
+
+	if !rpdo.running {
+		return
+	}
+
+	rpdo.bus.Unsubscribe(rpdo.handler)
+	rpdo.running = false
+	if rpdo.timer != nil {
+		rpdo.timer.Stop()
+		rpdo.timer = nil
+	}
+}
+
+// OnSync applies the values of a synchronous RPDO received before the SYNC, it can be subscribed to a syncobj.Consumer.
+func (rpdo *RPDO) OnSync(event syncobj.Event) {
+	if event.Type != syncobj.EventSync {
+		return
+	}
+
+	rpdo.lock.Lock()
+	data := rpdo.buffered
+	rpdo.buffered = nil
+	rpdo.lock.Unlock()
+
+	if data != nil {
+		rpdo.apply(data)
+	}
+}
+
+func (rpdo *RPDO) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || frame.CobID != rpdo.communication.CanID() {
+		return
+	}
+
+	data := append([]byte{}, frm.Data[:frm.Length]...)
+
+	rpdo.lock.Lock()
+	if !rpdo.running {
+		rpdo.lock.Unlock()
+		return
+	}
+
+	rpdo.restartTimer()
+	if rpdo.communication.IsSynchronous() {
+		rpdo.buffered = data
+		rpdo.lock.Unlock()
+		return
+	}
+	rpdo.lock.Unlock()
+
+	rpdo.apply(data)
+}
+
+func (rpdo *RPDO) apply(data []byte) {
+	if err := rpdo.mapping.Unpack(rpdo.dictionary, data); err != nil {
+		rpdo.error(err)
+		return
+	}
+
+	if rpdo.OnReceive != nil {
+		rpdo.OnReceive()
+	}
+}
+
+func (rpdo *RPDO) error(err error) {
+	if rpdo.OnError != nil {
+		rpdo.OnError(err)
+	}
+}
+
+// restartTimer restarts the receive timeout, the lock must be held
+func (rpdo *RPDO) restartTimer() {
+	if rpdo.communication.EventTimer <= 0 {
+		return
+	}
+
+	if rpdo.timer == nil {
+		rpdo.timer = time.AfterFunc(rpdo.communication.EventTimer, func() {
+			rpdo.lock.Lock()
+			running := rpdo.running
+			rpdo.lock.Unlock()
+
+			if running {
+				rpdo.error(ErrTimeout)
+			}
+		})
+	} else {
+		rpdo.timer.Reset(rpdo.communication.EventTimer)
+	}
+}
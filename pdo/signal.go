@@ -0,0 +1,291 @@
+package pdo
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"math"
+	"strconv"
+)
+
+// Signal describes a value inside the PDO of a node, similar to a signal of a DBC file.
+// The physical value is raw value * Scale + Offset.
+type Signal struct {
+	Name   string
+	NodeID uint8
+	// PDO is the number of the PDO (1-4 for the pre-defined COB-IDs)
+	PDO int
+	// RPDO selects an RPDO of the node (encoded by Encode), otherwise a TPDO of the node (decoded by Decode)
+	RPDO bool
+	// CobID overrides the pre-defined COB-ID of the PDO (optional)
+	CobID uint16
+
+	// BitOffset is the position of the least significant bit in the PDO data
+	BitOffset uint8
+	// BitLength is the number of bits of the raw value
+	BitLength uint8
+	// DataType is the type of the raw value, signed types are sign extended from the bit length
+	DataType sdo.SDODataType
+
+	// Scale is the factor of the raw value, 0 is treated as 1
+	Scale  float64
+	Offset float64
+	Unit   string
+}
+
+// Value is a decoded signal value.
+type Value struct {
+	Name  string
+	Value float64
+	Unit  string
+}
+
+// PDOCobID returns the COB-ID of the signal's PDO.
+func (signal Signal) PDOCobID() uint16 {
+	if signal.CobID != 0 {
+		return signal.CobID
+	}
+
+	return uint16(DefaultCobID(!signal.RPDO, signal.PDO, signal.NodeID))
+}
+
+// Validate checks that the signal fits into a PDO and its data type.
+func (signal Signal) Validate() error {
+	size := sdo.DataTypeSize(signal.DataType)
+	if size == 0 || signal.DataType == sdo.DATA_TYPE_TIME_OF_DAY || signal.DataType == sdo.DATA_TYPE_TIME_DIFFERENCE {
+		return fmt.Errorf("signal %s: data type %X is not supported", signal.Name, signal.DataType)
+	}
+
+	if signal.BitLength == 0 || int(signal.BitLength) > size*8 {
+		return fmt.Errorf("signal %s: bit length %d does not match the data type", signal.Name, signal.BitLength)
+	}
+
+	if isReal(signal.DataType) && int(signal.BitLength) != size*8 {
+		return fmt.Errorf("signal %s: floating point values can't be truncated", signal.Name)
+	}
+
+	if int(signal.BitOffset)+int(signal.BitLength) > MaxLength {
+		return fmt.Errorf("signal %s: bits %d-%d exceed a PDO", signal.Name, signal.BitOffset, int(signal.BitOffset)+int(signal.BitLength)-1)
+	}
+
+	return nil
+}
+
+// Matches returns true if the frame belongs to the signal's PDO.
+func (signal Signal) Matches(frame canopen.Frame) bool {
+	return !frame.Rtr && frame.CobID == signal.PDOCobID()
+}
+
+// Decode returns the physical value of the signal in the frame.
+// The data of the frame must have the received length, frames of canopen.CANopenFrame and canopen.Subscribe
+// always contain 8 bytes, so received frames should be decoded with DecodeCANFrame.
+func (signal Signal) Decode(frame canopen.Frame) (float64, error) {
+	if err := signal.Validate(); err != nil {
+		return 0, err
+	}
+
+	if !signal.Matches(frame) {
+		return 0, fmt.Errorf("signal %s: unexpected COB-ID %X", signal.Name, frame.CobID)
+	}
+
+	if end := (int(signal.BitOffset) + int(signal.BitLength) + 7) / 8; len(frame.Data) < end {
+		return 0, fmt.Errorf("signal %s: %w: %d bytes received, %d bytes expected", signal.Name, ErrLength, len(frame.Data), end)
+	}
+
+	bits := (toUint64(frame.Data) >> signal.BitOffset) & mask(signal.BitLength)
+	raw, err := signal.rawValue(bits)
+	if err != nil {
+		return 0, err
+	}
+
+	return raw*signal.scale() + signal.Offset, nil
+}
+
+// DecodeCANFrame returns the physical value of the signal in a received CAN frame,
+// a frame shorter than the signal returns ErrLength.
+func (signal Signal) DecodeCANFrame(frm can.Frame) (float64, error) {
+	return signal.Decode(receivedFrame(frm))
+}
+
+// Encode writes the physical value of the signal into the data.
+func (signal Signal) Encode(data []byte, value float64) error {
+	if err := signal.Validate(); err != nil {
+		return err
+	}
+
+	if end := (int(signal.BitOffset) + int(signal.BitLength) + 7) / 8; len(data) < end {
+		return fmt.Errorf("signal %s: data too short", signal.Name)
+	}
+
+	bits, err := signal.rawBits((value - signal.Offset) / signal.scale())
+	if err != nil {
+		return err
+	}
+
+	current := toUint64(data)
+	current &^= mask(signal.BitLength) << signal.BitOffset
+	current |= (bits & mask(signal.BitLength)) << signal.BitOffset
+
+	for i := range data {
+		data[i] = byte(current >> (8 * i))
+	}
+
+	return nil
+}
+
+func (signal Signal) scale() float64 {
+	if signal.Scale == 0 {
+		return 1
+	}
+
+	return signal.Scale
+}
+
+// rawValue converts the extracted bits with the sdo data type conversion
+func (signal Signal) rawValue(bits uint64) (float64, error) {
+	size := sdo.DataTypeSize(signal.DataType)
+	switch {
+	case signal.DataType == sdo.DATA_TYPE_REAL_32:
+		return float64(math.Float32frombits(uint32(bits))), nil
+	case signal.DataType == sdo.DATA_TYPE_REAL_64:
+		return math.Float64frombits(bits), nil
+	case sdo.IsReversed(signal.DataType):
+		// Extend the sign of truncated values
+		if bits&(1<<(signal.BitLength-1)) != 0 {
+			bits |= ^mask(signal.BitLength)
+		}
+
+		value, err := sdo.ParseInt(uintBytes(bits, size))
+		return float64(value), err
+	default:
+		return float64(sdo.ParseUInt(uintBytes(bits, size))), nil
+	}
+}
+
+// rawBits converts a raw value to the bits of the signal
+func (signal Signal) rawBits(raw float64) (uint64, error) {
+	switch signal.DataType {
+	case sdo.DATA_TYPE_REAL_32:
+		return uint64(math.Float32bits(float32(raw))), nil
+	case sdo.DATA_TYPE_REAL_64:
+		return math.Float64bits(raw), nil
+	}
+
+	raw = math.Round(raw)
+	var text string
+	if sdo.IsReversed(signal.DataType) {
+		limit := math.Ldexp(1, int(signal.BitLength)-1)
+		if raw < -limit || raw >= limit {
+			return 0, fmt.Errorf("signal %s: value %v out of range", signal.Name, raw)
+		}
+		text = strconv.FormatInt(int64(raw), 10)
+	} else {
+		if raw < 0 || raw >= math.Ldexp(1, int(signal.BitLength)) {
+			return 0, fmt.Errorf("signal %s: value %v out of range", signal.Name, raw)
+		}
+		text = strconv.FormatUint(uint64(raw), 10)
+	}
+
+	data, hasError := sdo.DataTypeToByte(signal.DataType, text)
+	if hasError {
+		return 0, fmt.Errorf("signal %s: value %s can't be converted", signal.Name, text)
+	}
+
+	return toUint64(data), nil
+}
+
+// Signals is a set of signal definitions.
+type Signals []Signal
+
+// Decode returns the values of all signals of the frame's PDO.
+// Signals which can't be decoded are skipped, the data of the frame must have the received length like for Signal.Decode.
+func (signals Signals) Decode(frame canopen.Frame) []Value {
+	var values []Value
+	for _, signal := range signals {
+		if signal.RPDO || !signal.Matches(frame) {
+			continue
+		}
+
+		if value, err := signal.Decode(frame); err == nil {
+			values = append(values, Value{Name: signal.Name, Value: value, Unit: signal.Unit})
+		}
+	}
+
+	return values
+}
+
+// DecodeCANFrame returns the values of all signals of a received CAN frame's PDO.
+// Signals which can't be decoded, e.g. because the frame is too short, are skipped.
+func (signals Signals) DecodeCANFrame(frm can.Frame) []Value {
+	return signals.Decode(receivedFrame(frm))
+}
+
+// Encode returns the RPDO frame of a node with the values of its signals.
+// Signals of the RPDO without value keep the raw value 0.
+func (signals Signals) Encode(nodeID uint8, number int, values map[string]float64) (canopen.Frame, error) {
+	var pdoSignals Signals
+	length := 0
+	for _, signal := range signals {
+		if !signal.RPDO || signal.NodeID != nodeID || signal.PDO != number {
+			continue
+		}
+
+		pdoSignals = append(pdoSignals, signal)
+		if end := int(signal.BitOffset) + int(signal.BitLength); end > length {
+			length = end
+		}
+	}
+
+	if len(pdoSignals) == 0 {
+		return canopen.Frame{}, fmt.Errorf("no signals for RPDO %d of node %d", number, nodeID)
+	}
+
+	for name := range values {
+		found := false
+		for _, signal := range pdoSignals {
+			found = found || signal.Name == name
+		}
+
+		if !found {
+			return canopen.Frame{}, fmt.Errorf("unknown signal %s for RPDO %d of node %d", name, number, nodeID)
+		}
+	}
+
+	data := make([]byte, (length+7)/8)
+	for _, signal := range pdoSignals {
+		value, ok := values[signal.Name]
+		if !ok {
+			continue
+		}
+
+		if err := signal.Encode(data, value); err != nil {
+			return canopen.Frame{}, err
+		}
+	}
+
+	return canopen.NewFrame(pdoSignals[0].PDOCobID(), data), nil
+}
+
+// receivedFrame returns the CANopen frame of a CAN frame with the received data length
+func receivedFrame(frm can.Frame) canopen.Frame {
+	frame := canopen.CANopenFrame(frm)
+	if int(frm.Length) < len(frame.Data) {
+		frame.Data = frame.Data[:frm.Length]
+	}
+
+	return frame
+}
+
+func isReal(dataType sdo.SDODataType) bool {
+	return dataType == sdo.DATA_TYPE_REAL_32 || dataType == sdo.DATA_TYPE_REAL_64
+}
+
+func uintBytes(bits uint64, size int) []byte {
+	data := make([]byte, 8)
+	for i := range data {
+		data[i] = byte(bits >> (8 * i))
+	}
+
+	return data[:size]
+}
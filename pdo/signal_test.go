@@ -0,0 +1,99 @@
+package pdo
+
+import (
+	"bytes"
+	"errors"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"math"
+	"testing"
+)
+
+var signals = Signals{
+	{Name: "Temperature", NodeID: 5, PDO: 1, BitOffset: 0, BitLength: 12, DataType: sdo.DATA_TYPE_INTEGER_16, Scale: 0.1, Unit: "°C"},
+	{Name: "Ready", NodeID: 5, PDO: 1, BitOffset: 12, BitLength: 1, DataType: sdo.DATA_TYPE_BOOLEAN},
+	{Name: "Position", NodeID: 5, PDO: 1, BitOffset: 16, BitLength: 24, DataType: sdo.DATA_TYPE_INTEGER_24, Unit: "inc"},
+	{Name: "Energy", NodeID: 5, PDO: 2, BitOffset: 0, BitLength: 40, DataType: sdo.DATA_TYPE_UNSIGNED_40, Scale: 0.001, Offset: 10, Unit: "kWh"},
+	{Name: "Setpoint", NodeID: 5, PDO: 1, RPDO: true, BitOffset: 0, BitLength: 32, DataType: sdo.DATA_TYPE_REAL_32, Unit: "rpm"},
+	{Name: "Mode", NodeID: 5, PDO: 1, RPDO: true, BitOffset: 32, BitLength: 4, DataType: sdo.DATA_TYPE_UNSIGNED_8},
+}
+
+func TestSignalDecode(t *testing.T) {
+	// Temperature -12.3 (-123 = 0xF85 in 12 bits), ready, position -2
+	frame := canopen.NewFrame(0x185, []byte{0x85, 0x1F, 0xFE, 0xFF, 0xFF})
+	values := signals.Decode(frame)
+
+	expected := []Value{{"Temperature", -12.3, "°C"}, {"Ready", 1, ""}, {"Position", -2, "inc"}}
+	if len(values) != len(expected) {
+		t.Log("Unexpected values", values)
+		t.FailNow()
+	}
+
+	for i, value := range values {
+		if value.Name != expected[i].Name || math.Abs(value.Value-expected[i].Value) > 1e-9 || value.Unit != expected[i].Unit {
+			t.Log("Unexpected value", value, "expected", expected[i])
+			t.FailNow()
+		}
+	}
+
+	energy, err := signals[3].Decode(canopen.NewFrame(0x285, []byte{0x40, 0x42, 0x0F, 0x00, 0x01}))
+	if err != nil || math.Abs(energy-(4295967.296+10)) > 1e-6 {
+		t.Log("Unexpected energy", energy, err)
+		t.FailNow()
+	}
+
+	if _, err := signals[2].Decode(canopen.NewFrame(0x185, []byte{0x00, 0x00, 0x00})); err == nil {
+		t.Log("Expected length error")
+		t.FailNow()
+	}
+}
+
+func TestSignalDecodeCANFrame(t *testing.T) {
+	// The received frame is too short for the position, CANopenFrame pads the data to 8 bytes
+	frm := canopen.NewFrame(0x185, []byte{0x85, 0x1F, 0xFE}).CANFrame()
+	if frame := canopen.CANopenFrame(frm); len(frame.Data) != 8 {
+		t.Log("Unexpected data length", len(frame.Data))
+		t.FailNow()
+	}
+
+	if _, err := signals[2].DecodeCANFrame(frm); !errors.Is(err, ErrLength) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	values := signals.DecodeCANFrame(frm)
+	if len(values) != 2 || values[0].Name != "Temperature" || values[1].Name != "Ready" {
+		t.Log("Unexpected values", values)
+		t.FailNow()
+	}
+}
+
+func TestSignalEncode(t *testing.T) {
+	frame, err := signals.Encode(5, 1, map[string]float64{"Setpoint": 1.5, "Mode": 6})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if frame.CobID != 0x205 || !bytes.Equal(frame.Data, []byte{0x00, 0x00, 0xC0, 0x3F, 0x06}) {
+		t.Log("Unexpected frame", frame)
+		t.FailNow()
+	}
+
+	if _, err := signals.Encode(5, 1, map[string]float64{"Mode": 16}); err == nil {
+		t.Log("Expected range error")
+		t.FailNow()
+	}
+
+	if _, err := signals.Encode(5, 1, map[string]float64{"Temperature": 1}); err == nil {
+		t.Log("Expected unknown signal error")
+		t.FailNow()
+	}
+
+	// Encoding and decoding a truncated signed value
+	data := make([]byte, 2)
+	if err := signals[0].Encode(data, -12.3); err != nil || !bytes.Equal(data, []byte{0x85, 0x0F}) {
+		t.Log("Unexpected data", data, err)
+		t.FailNow()
+	}
+}
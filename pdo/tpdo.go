@@ -0,0 +1,264 @@
+package pdo
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/syncobj"
+	"sync"
+	"time"
+)
+
+// TPDO transmits the values of the mapped objects.
+// Depending on the transmission type the PDO is sent on SYNC, on events, by the event timer or on remote transmit requests.
+type TPDO struct {
+	communication CommunicationParameters
+	mapping       Mapping
+	dictionary    *od.ObjectDictionary
+
+	bus     *can.Bus
+	handler can.Handler
+
+	lock         sync.Mutex
+	running      bool
+	syncCount    int
+	syncStarted  bool
+	eventPending bool
+	sampled      []byte
+	lastSent     time.Time
+	inhibitTimer *time.Timer
+	eventTimer   *time.Timer
+}
+
+// NewTPDO returns a TPDO which transmits the mapped objects of the object dictionary.
+func NewTPDO(dictionary *od.ObjectDictionary, communication CommunicationParameters, mapping Mapping) (*TPDO, error) {
+	if err := communication.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := mapping.Validate(dictionary); err != nil {
+		return nil, err
+	}
+
+	return &TPDO{
+		communication: communication,
+		mapping:       mapping,
+		dictionary:    dictionary,
+	}, nil
+}
+
+// LoadTPDO returns the TPDO with the number (1-512) as configured in the object dictionary (0x1800+, 0x1A00+).
+func LoadTPDO(dictionary *od.ObjectDictionary, number int) (*TPDO, error) {
+	communication, err := ReadCommunicationParameters(dictionary, TPDOCommunicationIndex+uint16(number-1))
+	if err != nil {
+		return nil, err
+	}
+
+	mapping, err := ReadMapping(dictionary, TPDOMappingIndex+uint16(number-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTPDO(dictionary, communication, mapping)
+}
+
+// Communication returns the communication parameters.
+func (tpdo *TPDO) Communication() CommunicationParameters {
+	return tpdo.communication
+}
+
+// Mapping returns the mapped objects.
+func (tpdo *TPDO) Mapping() Mapping {
+	return tpdo.mapping
+}
+
+// Start enables the TPDO, it answers remote transmit requests and starts the event timer.
+func (tpdo *TPDO) Start(bus *can.Bus) {
+	tpdo.lock.Lock()
+	defer tpdo.lock.Unlock()
+
+	if tpdo.running || !tpdo.communication.Valid() {
+		return
+	}
+
+	tpdo.bus = bus
+	tpdo.running = true
+	tpdo.syncCount = 0
+	tpdo.syncStarted = tpdo.communication.SyncStartValue == 0
+	tpdo.eventPending = false
+	tpdo.sampled = nil
+
+	tpdo.handler = can.NewHandler(tpdo.handle)
+	bus.Subscribe(tpdo.handler)
+
+	tpdo.restartEventTimer()
+}
+
+// Stop disables the TPDO.
+func (tpdo *TPDO) Stop() {
+	tpdo.lock.Lock()
+	defer tpdo.lock.Unlock()
+
+	if !tpdo.running {
+		return
+	}
+
+	tpdo.bus.Unsubscribe(tpdo.handler)
+	tpdo.running = false
+	for _, timer := range []*time.Timer{tpdo.inhibitTimer, tpdo.eventTimer} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	tpdo.inhibitTimer = nil
+	tpdo.eventTimer = nil
+}
+
+// Trigger signals an application event, e.g. a changed value.
+// Event driven TPDOs are sent as soon as the inhibit time allows, acyclic synchronous TPDOs on the next SYNC.
+func (tpdo *TPDO) Trigger() {
+	tpdo.lock.Lock()
+	defer tpdo.lock.Unlock()
+
+	if !tpdo.running {
+		return
+	}
+
+	switch {
+	case tpdo.communication.TransmissionType == TransmissionSyncAcyclic:
+		tpdo.eventPending = true
+	case tpdo.communication.IsEventDriven():
+		tpdo.sendEvent()
+	}
+}
+
+// OnSync processes a SYNC event, it can be subscribed to a syncobj.Consumer.
+func (tpdo *TPDO) OnSync(event syncobj.Event) {
+	if event.Type != syncobj.EventSync {
+		return
+	}
+
+	tpdo.lock.Lock()
+	defer tpdo.lock.Unlock()
+
+	if !tpdo.running {
+		return
+	}
+
+	transmissionType := tpdo.communication.TransmissionType
+	switch {
+	case transmissionType == TransmissionSyncAcyclic:
+		if tpdo.eventPending {
+			tpdo.eventPending = false
+			tpdo.send()
+		}
+
+	case transmissionType <= TransmissionSyncMax:
+		// With a SYNC start value the counting starts with the SYNC of the same counter value
+		if !tpdo.syncStarted {
+			if !event.HasCounter || event.Counter != tpdo.communication.SyncStartValue {
+				return
+			}
+			tpdo.syncStarted = true
+			tpdo.syncCount = 0
+		}
+
+		if tpdo.syncCount%int(transmissionType) == 0 {
+			tpdo.syncCount = 0
+			tpdo.send()
+		}
+		tpdo.syncCount++
+
+	case transmissionType == TransmissionRTRSync:
+		if data, err := tpdo.mapping.Pack(tpdo.dictionary); err == nil {
+			tpdo.sampled = data
+		}
+	}
+}
+
+func (tpdo *TPDO) handle(frm can.Frame) {
+	frame := canopen.CANopenFrame(frm)
+	if frm.ID&(canopen.MaskEff|canopen.MaskErr) != 0 || !frame.Rtr || frame.CobID != tpdo.communication.CanID() {
+		return
+	}
+
+	tpdo.lock.Lock()
+	defer tpdo.lock.Unlock()
+
+	if !tpdo.running || tpdo.communication.CobID&CobIDNoRTR != 0 {
+		return
+	}
+
+	// The data sampled on the last SYNC is sent
+	if tpdo.communication.TransmissionType == TransmissionRTRSync {
+		if tpdo.sampled != nil {
+			tpdo.publish(tpdo.sampled)
+		}
+		return
+	}
+
+	tpdo.send()
+}
+
+// sendEvent sends an event driven TPDO respecting the inhibit time, the lock must be held
+func (tpdo *TPDO) sendEvent() {
+	if tpdo.inhibitTimer != nil {
+		// The TPDO is already scheduled
+		return
+	}
+
+	if wait := tpdo.communication.InhibitTime - time.Since(tpdo.lastSent); !tpdo.lastSent.IsZero() && wait > 0 {
+		tpdo.inhibitTimer = time.AfterFunc(wait, func() {
+			tpdo.lock.Lock()
+			defer tpdo.lock.Unlock()
+
+			tpdo.inhibitTimer = nil
+			if tpdo.running {
+				tpdo.send()
+			}
+		})
+		return
+	}
+
+	tpdo.send()
+}
+
+// send packs and publishes the mapped objects, the lock must be held
+func (tpdo *TPDO) send() {
+	data, err := tpdo.mapping.Pack(tpdo.dictionary)
+	if err != nil {
+		return
+	}
+
+	tpdo.publish(data)
+}
+
+// publish sends the data, the lock must be held
+func (tpdo *TPDO) publish(data []byte) {
+	frame := canopen.NewFrame(tpdo.communication.CanID(), data)
+	if err := tpdo.bus.PublishMinDuration(frame.CANFrame(), 0); err == nil {
+		tpdo.lastSent = time.Now()
+	}
+
+	tpdo.restartEventTimer()
+}
+
+// restartEventTimer restarts the event timer of event driven TPDOs, the lock must be held
+func (tpdo *TPDO) restartEventTimer() {
+	if !tpdo.communication.IsEventDriven() || tpdo.communication.EventTimer <= 0 {
+		return
+	}
+
+	if tpdo.eventTimer == nil {
+		tpdo.eventTimer = time.AfterFunc(tpdo.communication.EventTimer, func() {
+			tpdo.lock.Lock()
+			defer tpdo.lock.Unlock()
+
+			if tpdo.running {
+				tpdo.sendEvent()
+			}
+		})
+	} else {
+		tpdo.eventTimer.Reset(tpdo.communication.EventTimer)
+	}
+}
@@ -0,0 +1,23 @@
+package sdo
+
+// CRC16 calculates the CRC-16 checksum of SDO block transfers as defined by CiA 301
+// (polynomial x^16 + x^12 + x^5 + 1, initial value 0).
+func CRC16(data []byte) uint16 {
+	return UpdateCRC16(0, data)
+}
+
+// UpdateCRC16 continues the calculation of the checksum crc with additional data.
+func UpdateCRC16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
@@ -0,0 +1,21 @@
+package sdo
+
+import "testing"
+
+func TestCRC16(t *testing.T) {
+	if crc := CRC16([]byte("123456789")); crc != 0x31C3 {
+		t.Log("CRC does not match", crc)
+		t.FailNow()
+	}
+
+	if crc := CRC16([]byte{}); crc != 0 {
+		t.Log("CRC of no data is not 0", crc)
+		t.FailNow()
+	}
+
+	// The CRC can be calculated segment by segment
+	if crc := UpdateCRC16(CRC16([]byte("1234")), []byte("56789")); crc != 0x31C3 {
+		t.Log("CRC does not match", crc)
+		t.FailNow()
+	}
+}
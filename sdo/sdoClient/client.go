@@ -0,0 +1,204 @@
+package sdoClient
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/avast/retry-go"
+	"time"
+)
+
+const (
+	// ClientParameterIndex is the index of the first SDO client parameter object, the objects are at 0x1280-0x12FF
+	ClientParameterIndex uint16 = 0x1280
+	// MaxClients is the number of SDO client parameter objects
+	MaxClients = 128
+	// DefaultBlockThreshold is the size from which TransferAuto uses a block download if no threshold is set
+	DefaultBlockThreshold = 128
+
+	cobIDInvalid uint32 = 1 << 31
+)
+
+// TransferMode selects the SDO transfer used by a client
+type TransferMode uint8
+
+const (
+	// TransferAuto uses an expedited or segmented transfer, block downloads are used from the block threshold.
+	// Uploads are never block transfers, because the size is unknown before the upload
+	// and the protocol switch to a segmented upload for small objects is not supported.
+	// TransferBlock must be used for block uploads.
+	TransferAuto TransferMode = iota
+	// TransferSegmented uses an expedited transfer for up to 4 bytes, otherwise a segmented transfer
+	TransferSegmented
+	// TransferBlock always uses a block transfer
+	TransferBlock
+)
+
+func (mode TransferMode) String() string {
+	switch mode {
+	case TransferAuto:
+		return "Auto"
+	case TransferSegmented:
+		return "Segmented"
+	case TransferBlock:
+		return "Block"
+	}
+
+	return "Unknown"
+}
+
+// Client reads and writes the objects of a remote node with SDO transfers.
+// It can be reused for any number of transfers, transfers of the same client are executed one after another.
+type Client struct {
+	Bus *can.Bus
+
+	// RequestCobID is the COB-ID of the requests from the client to the server (0x600 + node id)
+	RequestCobID uint16
+	// ResponseCobID is the COB-ID of the responses from the server to the client (0x580 + node id)
+	ResponseCobID uint16
+
+	// Timeout is the time to wait for a response of the server, DefaultTimeout is used if not set
+	Timeout time.Duration
+	// Delay is the minimum duration of every frame sent to the server, the defaults of the transfer are used if not set
+	Delay time.Duration
+	// Retries is the number of times a failed transfer is repeated, transfers aborted by the server are not repeated
+	Retries int
+	// RetryDelay is the time between a failed transfer and its repetition
+	RetryDelay time.Duration
+
+	// Mode selects the transfer
+	Mode TransferMode
+	// BlockThreshold is the size from which TransferAuto uses a block download, DefaultBlockThreshold is used if not set
+	BlockThreshold int
+	// BlockSize is the number of segments per block of a block upload, the maximum is used if not set
+	BlockSize uint8
+}
+
+// NewClient returns a client for the default SDO of the node.
+func NewClient(bus *can.Bus, nodeID uint8) *Client {
+	return &Client{
+		Bus:           bus,
+		RequestCobID:  canopen.MessageTypeRSDO + uint16(nodeID),
+		ResponseCobID: canopen.MessageTypeTSDO + uint16(nodeID),
+	}
+}
+
+// LoadClient returns a client with the COB-IDs of the SDO client parameter object with the number (1-128, 0x1280+).
+func LoadClient(bus *can.Bus, dictionary *od.ObjectDictionary, number int) (*Client, error) {
+	if number < 1 || number > MaxClients {
+		return nil, fmt.Errorf("invalid SDO client number %d", number)
+	}
+
+	index := ClientParameterIndex + uint16(number-1)
+	var cobIDs [2]uint32
+	for i := range cobIDs {
+		objectIndex := canopen.NewObjectIndex(index, uint8(i+1))
+		data, abortCode := dictionary.Value(objectIndex)
+		if abortCode != canopen.NO_ERROR {
+			return nil, fmt.Errorf("reading %s: %s", objectIndex.String(), canopen.GetAbortCodeText(abortCode))
+		} else if len(data) != 4 {
+			return nil, fmt.Errorf("invalid length %d of %s", len(data), objectIndex.String())
+		}
+
+		cobIDs[i] = binary.LittleEndian.Uint32(data)
+		if cobIDs[i]&cobIDInvalid != 0 {
+			return nil, fmt.Errorf("SDO client %d is not valid", number)
+		}
+	}
+
+	return &Client{
+		Bus:           bus,
+		RequestCobID:  uint16(cobIDs[0] & canopen.MaskIDSff),
+		ResponseCobID: uint16(cobIDs[1] & canopen.MaskIDSff),
+	}, nil
+}
+
+// Read reads the value of an object.
+func (client *Client) Read(objectIndex canopen.ObjectIndex) ([]byte, error) {
+	return client.ReadContext(context.Background(), objectIndex)
+}
+
+// ReadContext reads the value of an object until ctx is done.
+func (client *Client) ReadContext(ctx context.Context, objectIndex canopen.ObjectIndex) ([]byte, error) {
+	upload := Upload{
+		ObjectIndex:   objectIndex,
+		RequestCobID:  client.RequestCobID,
+		ResponseCobID: client.ResponseCobID,
+		BlockSize:     client.BlockSize,
+		Timeout:       client.Timeout,
+		Delay:         client.Delay,
+	}
+
+	var data []byte
+	err := client.retry(ctx, func() error {
+		var err error
+		// The size is unknown before the upload, so only a block upload is requested explicitly (see TransferAuto)
+		if client.Mode == TransferBlock {
+			data, err = upload.DoBlockContext(ctx, client.Bus)
+		} else {
+			data, err = upload.DoContext(ctx, client.Bus)
+		}
+
+		return err
+	})
+
+	return data, err
+}
+
+// Write writes the value of an object.
+func (client *Client) Write(objectIndex canopen.ObjectIndex, data []byte) error {
+	return client.WriteContext(context.Background(), objectIndex, data)
+}
+
+// WriteContext writes the value of an object until ctx is done.
+func (client *Client) WriteContext(ctx context.Context, objectIndex canopen.ObjectIndex, data []byte) error {
+	download := Download{
+		ObjectIndex:   objectIndex,
+		Data:          data,
+		RequestCobID:  client.RequestCobID,
+		ResponseCobID: client.ResponseCobID,
+		Timeout:       client.Timeout,
+		Delay:         client.Delay,
+	}
+
+	block := client.Mode == TransferBlock
+	if client.Mode == TransferAuto {
+		threshold := client.BlockThreshold
+		if threshold <= 0 {
+			threshold = DefaultBlockThreshold
+		}
+
+		block = len(data) >= threshold
+	}
+
+	return client.retry(ctx, func() error {
+		if block {
+			return download.DoBlockContext(ctx, client.Bus)
+		}
+
+		return download.DoContext(ctx, client.Bus)
+	})
+}
+
+// retry repeats a failed transfer unless the server aborted it or ctx is done
+func (client *Client) retry(ctx context.Context, transfer func() error) error {
+	if client.Retries <= 0 {
+		return transfer()
+	}
+
+	return retry.Do(transfer,
+		retry.Attempts(uint(client.Retries)+1),
+		retry.Delay(client.RetryDelay),
+		retry.DelayType(retry.FixedDelay),
+		retry.Context(ctx),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(func(err error) bool {
+			var abort canopen.TransferAbort
+			return !errors.As(err, &abort) && ctx.Err() == nil
+		}),
+	)
+}
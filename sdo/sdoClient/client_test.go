@@ -0,0 +1,222 @@
+package sdoClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
+	"github.com/FabianPetersen/canopen/sdo"
+	"github.com/FabianPetersen/canopen/sdo/sdoServer"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	defer clientBus.Disconnect()
+
+	var lock sync.Mutex
+	values := map[canopen.ObjectIndex][]byte{}
+	server := &sdoServer.Server{
+		NodeId: 3,
+		Upload: func(objectIndex canopen.ObjectIndex) ([]byte, canopen.SDOAbortCode) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if value, ok := values[objectIndex]; ok {
+				return value, canopen.NO_ERROR
+			}
+			return nil, canopen.SDO_ERR_NO_OBJECT
+		},
+		Download: func(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+			lock.Lock()
+			defer lock.Unlock()
+
+			values[objectIndex] = data
+			return canopen.NO_ERROR
+		},
+	}
+	go server.Listen(serverBus)
+	time.Sleep(10 * time.Millisecond)
+
+	client := NewClient(clientBus, 3)
+	client.Timeout = 200 * time.Millisecond
+	client.Delay = time.Millisecond
+	client.BlockThreshold = 32
+
+	// Expedited, segmented and block transfers are selected by the size
+	for i, size := range []int{2, 20, 200} {
+		objectIndex := canopen.NewObjectIndex(0x2000, uint8(i+1))
+		data := make([]byte, size)
+		for j := range data {
+			data[j] = byte(j * 7)
+		}
+
+		if err := client.Write(objectIndex, data); err != nil {
+			t.Log(size, err)
+			t.FailNow()
+		}
+
+		if value, err := client.Read(objectIndex); err != nil || !bytes.Equal(value, data) {
+			t.Log("Unexpected value", size, value, err)
+			t.FailNow()
+		}
+	}
+
+	client.Mode = TransferBlock
+	if value, err := client.Read(canopen.NewObjectIndex(0x2000, 3)); err != nil || len(value) != 200 {
+		t.Log("Unexpected block upload", len(value), err)
+		t.FailNow()
+	}
+
+	// A block download without data sends one empty segment
+	if err := client.Write(canopen.NewObjectIndex(0x2000, 4), []byte{}); err != nil {
+		t.Log("Unexpected empty block download", err)
+		t.FailNow()
+	}
+
+	lock.Lock()
+	value, ok := values[canopen.NewObjectIndex(0x2000, 4)]
+	lock.Unlock()
+	if !ok || len(value) != 0 {
+		t.Log("Unexpected empty value", value, ok)
+		t.FailNow()
+	}
+
+	// Aborted transfers are not repeated
+	client.Mode = TransferAuto
+	client.Retries = 2
+	var abort canopen.TransferAbort
+	if _, err := client.Read(canopen.NewObjectIndex(0x2001, 0)); !errors.As(err, &abort) || abort.Code() != canopen.SDO_ERR_NO_OBJECT {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	// Timeouts are repeated, node 4 doesn't exist
+	requests := canopen.Subscribe(serverBus, canopen.MessageTypeRSDO+4, 10)
+	defer requests.Close()
+
+	missing := NewClient(clientBus, 4)
+	missing.Timeout = 20 * time.Millisecond
+	missing.Retries = 2
+	if _, err := missing.Read(canopen.NewObjectIndex(0x2000, 1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	if n := len(requests.C); n != 3 {
+		t.Log("Unexpected number of requests", n)
+		t.FailNow()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := missing.WriteContext(ctx, canopen.NewObjectIndex(0x2000, 1), []byte{1}); !errors.Is(err, context.Canceled) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
+
+func TestLoadClient(t *testing.T) {
+	dictionary := od.NewObjectDictionary()
+	parameters := od.NewRecord(ClientParameterIndex+1, "SDO client parameter")
+	parameters.AddSubIndex(&od.Variable{SubIndex: 0, DataType: sdo.DATA_TYPE_UNSIGNED_8, AccessType: od.ACCESS_TYPE_CONST, DefaultValue: []byte{3}})
+	parameters.AddSubIndex(&od.Variable{SubIndex: 1, DataType: sdo.DATA_TYPE_UNSIGNED_32, AccessType: od.ACCESS_TYPE_RW, DefaultValue: binary.LittleEndian.AppendUint32(nil, 0x645)})
+	parameters.AddSubIndex(&od.Variable{SubIndex: 2, DataType: sdo.DATA_TYPE_UNSIGNED_32, AccessType: od.ACCESS_TYPE_RW, DefaultValue: binary.LittleEndian.AppendUint32(nil, 0x5C5)})
+	parameters.AddSubIndex(&od.Variable{SubIndex: 3, DataType: sdo.DATA_TYPE_UNSIGNED_8, AccessType: od.ACCESS_TYPE_RW, DefaultValue: []byte{0x45}})
+	dictionary.Add(parameters)
+
+	client, err := LoadClient(nil, dictionary, 2)
+	if err != nil || client.RequestCobID != 0x645 || client.ResponseCobID != 0x5C5 {
+		t.Log("Unexpected client", client, err)
+		t.FailNow()
+	}
+
+	if _, err := LoadClient(nil, dictionary, 1); err == nil {
+		t.Log("Missing client parameter was loaded")
+		t.FailNow()
+	}
+
+	dictionary.SetValue(canopen.NewObjectIndex(ClientParameterIndex+1, 1), binary.LittleEndian.AppendUint32(nil, 0x80000645))
+	if _, err := LoadClient(nil, dictionary, 2); err == nil {
+		t.Log("Invalid client parameter was loaded")
+		t.FailNow()
+	}
+}
+
+func TestValues(t *testing.T) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	defer clientBus.Disconnect()
+
+	dictionary := od.NewObjectDictionary()
+	values := od.NewRecord(0x2000, "Values")
+	for subIndex, dataType := range []sdo.SDODataType{sdo.DATA_TYPE_INTEGER_24, sdo.DATA_TYPE_UNSIGNED_16, sdo.DATA_TYPE_REAL_32, sdo.DATA_TYPE_VISIBLE_STRING} {
+		values.AddSubIndex(&od.Variable{SubIndex: uint8(subIndex + 1), DataType: dataType, AccessType: od.ACCESS_TYPE_RW, DefaultValue: make([]byte, sdo.DataTypeSize(dataType))})
+	}
+	dictionary.Add(values)
+
+	server := &sdoServer.Server{NodeId: 3, ObjectDictionary: dictionary}
+	go server.Listen(serverBus)
+	defer serverBus.Disconnect()
+	time.Sleep(10 * time.Millisecond)
+
+	client := NewClient(clientBus, 3)
+	client.Timeout = 200 * time.Millisecond
+	if err := client.WriteInt24(canopen.NewObjectIndex(0x2000, 1), -1000); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if value, err := client.ReadInt24(canopen.NewObjectIndex(0x2000, 1)); err != nil || value != -1000 {
+		t.Log("Unexpected INTEGER_24", value, err)
+		t.FailNow()
+	}
+
+	if err := client.WriteFloat32(canopen.NewObjectIndex(0x2000, 3), 2.5); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if value, err := client.ReadFloat32(canopen.NewObjectIndex(0x2000, 3)); err != nil || value != 2.5 {
+		t.Log("Unexpected REAL_32", value, err)
+		t.FailNow()
+	}
+
+	if err := client.WriteVisibleString(canopen.NewObjectIndex(0x2000, 4), "device name"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if value, err := client.ReadVisibleString(canopen.NewObjectIndex(0x2000, 4)); err != nil || value != "device name" {
+		t.Log("Unexpected VISIBLE_STRING", value, err)
+		t.FailNow()
+	}
+
+	// The length of the remote value doesn't match the requested data type
+	if _, err := client.ReadUint32(canopen.NewObjectIndex(0x2000, 2)); !errors.Is(err, sdo.ErrLength) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	// Values out of range are rejected before the transfer
+	if err := client.WriteInt24(canopen.NewObjectIndex(0x2000, 1), 1<<23); !errors.Is(err, sdo.ErrRange) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+
+	if value, err := ReadValue[int64](context.Background(), client, canopen.NewObjectIndex(0x2000, 1), sdo.DATA_TYPE_INTEGER_24); err != nil || value != -1000 {
+		t.Log("Unexpected value", value, err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,59 @@
+package sdoClient
+
+import (
+	"context"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"strconv"
+	"time"
+)
+
+// DefaultTimeout is the time to wait for a response of the server if no timeout is set
+const DefaultTimeout = 2 * time.Second
+
+// lock acquires the lock of the SDO channel until ctx is done
+func lock(ctx context.Context, requestCobID uint16) error {
+	return canopen.LockContext(ctx, strconv.Itoa(int(requestCobID)))
+}
+
+func unlock(requestCobID uint16) {
+	canopen.Lock.Unlock(strconv.Itoa(int(requestCobID)))
+}
+
+// transferTimeout returns the timeout or DefaultTimeout if it isn't set
+func transferTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return DefaultTimeout
+	}
+
+	return timeout
+}
+
+// minDuration returns the delay or the default minimum duration of a frame if it isn't set
+func minDuration(delay time.Duration, fallback time.Duration) time.Duration {
+	if delay <= 0 {
+		return fallback
+	}
+
+	return delay
+}
+
+// receive waits for the next frame of the server until the timeout passed or ctx is done
+func receive(ctx context.Context, sub *canopen.Subscription, timeout time.Duration) (canopen.Frame, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	return sub.ReceiveContext(ctx)
+}
+
+// abortContext informs the server that the transfer was given up because ctx is done
+func abortContext(ctx context.Context, bus *can.Bus, requestCobID uint16, objectIndex canopen.ObjectIndex) {
+	code := canopen.SDO_ERR_GENERAL
+	if ctx.Err() == context.DeadlineExceeded {
+		code = canopen.SDO_ERR_TIMEOUT
+	}
+
+	frame := canopen.NewFrame(requestCobID, sdo.Pad(sdo.AbortData(code, objectIndex), 8))
+	_ = bus.PublishMinDuration(frame.CANFrame(), 0)
+}
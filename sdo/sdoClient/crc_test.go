@@ -0,0 +1,72 @@
+package sdoClient
+
+import (
+	"bytes"
+	"github.com/FabianPetersen/canopen"
+	"testing"
+	"time"
+)
+
+func TestBlockWithoutCRC(t *testing.T) {
+	bus, server := newScriptedServer(t)
+	objectIndex := canopen.NewObjectIndex(0x2000, 1)
+
+	// 10 bytes are 2 segments, the last segment has 4 unused bytes
+	data := []byte("0123456789")
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Download{
+			ObjectIndex:   objectIndex,
+			Data:          data,
+			RequestCobID:  canopen.MessageTypeRSDO + 1,
+			ResponseCobID: canopen.MessageTypeTSDO + 1,
+			Timeout:       500 * time.Millisecond,
+		}.DoBlock(bus)
+	}()
+
+	// ccs = 6, cc = 1 (client supports CRC), s = 1, cs = 0, size = 10
+	server.expect(0xC6, 0x00, 0x20, 0x01, 10, 0, 0, 0)
+	// scs = 5, sc = 0 (server doesn't support CRC), ss = 0, blksize = 127
+	server.respond(0xA0, 0x00, 0x20, 0x01, 127)
+	server.expect(append([]byte{0x01}, data[0:7]...)...)
+	server.expect(append([]byte{0x82}, data[7:10]...)...)
+	server.respond(0xA2, 2, 127)
+	// ccs = 6, n = 4, cs = 1 (end), no CRC
+	server.expect(0xD1, 0, 0)
+	server.respond(0xA1)
+	if err := <-errs; err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		data, err := Upload{
+			ObjectIndex:   objectIndex,
+			RequestCobID:  canopen.MessageTypeRSDO + 1,
+			ResponseCobID: canopen.MessageTypeTSDO + 1,
+			Timeout:       500 * time.Millisecond,
+		}.DoBlock(bus)
+		results <- result{data, err}
+	}()
+
+	// The client asks for CRC, ccs = 5, cc = 1, cs = 0
+	server.expect(0xA4, 0x00, 0x20, 0x01, 127)
+	// scs = 6, sc = 0, s = 1, ss = 0, size = 10
+	server.respond(0xC2, 0x00, 0x20, 0x01, 10, 0, 0, 0)
+	server.expect(0xA3)
+	server.respond(append([]byte{0x01}, data[0:7]...)...)
+	server.respond(append([]byte{0x82}, data[7:10]...)...)
+	server.expect(0xA2, 2, 127)
+	// The CRC bytes are ignored if the server doesn't support CRC
+	server.respond(0xD1, 0xFF, 0xFF)
+	server.expect(0xA1)
+	if r := <-results; r.err != nil || !bytes.Equal(r.data, data) {
+		t.Log("Unexpected data", r.data, r.err)
+		t.FailNow()
+	}
+}
@@ -2,12 +2,12 @@ package sdoClient
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
 	"github.com/FabianPetersen/canopen/sdo"
 	"github.com/avast/retry-go"
-	"strconv"
 	"time"
 )
 
@@ -27,53 +27,84 @@ type Download struct {
 	Data          []byte
 	RequestCobID  uint16
 	ResponseCobID uint16
+
+	// Timeout is the time to wait for a response of the server, DefaultTimeout is used if not set
+	Timeout time.Duration
+	// Delay is the minimum duration of every frame sent to the server, the defaults of the transfer are used if not set
+	Delay time.Duration
 }
 
 func (download Download) Do(bus *can.Bus) error {
-	// Do not allow multiple messages for the same device
-	key := strconv.Itoa(int(download.RequestCobID))
-	canopen.Lock.Lock(key)
-	defer canopen.Lock.Unlock(key)
+	return download.DoContext(context.Background(), bus)
+}
 
-	if err, _ := download.doInitFrame(bus, false); err != nil {
+// DoContext writes the data with an expedited or segmented download until ctx is done.
+// If ctx is done during the transfer, the transfer is aborted and the error wraps ctx.Err().
+func (download Download) DoContext(ctx context.Context, bus *can.Bus) error {
+	// Do not allow multiple messages for the same device
+	if err := lock(ctx, download.RequestCobID); err != nil {
 		return err
 	}
+	defer unlock(download.RequestCobID)
+
+	err, _, _ := download.doInitFrame(ctx, bus, false)
+	if err == nil {
+		err = download.doSegments(ctx, bus)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		abortContext(ctx, bus, download.RequestCobID, download.ObjectIndex)
+	}
 
-	return download.doSegments(bus)
+	return err
 }
 
 func (download Download) DoBlock(bus *can.Bus) error {
-	// Do not allow multiple messages for the same device
-	key := strconv.Itoa(int(download.RequestCobID))
-	canopen.Lock.Lock(key)
-	defer canopen.Lock.Unlock(key)
+	return download.DoBlockContext(context.Background(), bus)
+}
 
-	var err error
-	var segmentsPerBlock int
-	if err, segmentsPerBlock = download.doInitFrame(bus, true); err != nil {
+// DoBlockContext writes the data using an SDO block download until ctx is done.
+// If ctx is done during the transfer, the transfer is aborted and the error wraps ctx.Err().
+func (download Download) DoBlockContext(ctx context.Context, bus *can.Bus) error {
+	// Do not allow multiple messages for the same device
+	if err := lock(ctx, download.RequestCobID); err != nil {
 		return err
 	}
+	defer unlock(download.RequestCobID)
 
-	return download.doBlock(bus, segmentsPerBlock)
+	err, segmentsPerBlock, hasCRC := download.doInitFrame(ctx, bus, true)
+	if err == nil {
+		err = download.doBlock(ctx, bus, segmentsPerBlock, hasCRC)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		abortContext(ctx, bus, download.RequestCobID, download.ObjectIndex)
+	}
+
+	return err
 }
 
-func (download Download) doInitFrame(bus *can.Bus, isBlockTransfer bool) (error, int) {
+func (download Download) doInitFrame(ctx context.Context, bus *can.Bus, isBlockTransfer bool) (error, int, bool) {
 	segmentsPerBlock := 0
+	hasCRC := false
 	frame, err := download.initFrame(isBlockTransfer)
 	if err != nil {
-		return err, segmentsPerBlock
+		return err, segmentsPerBlock, hasCRC
 	}
 
 	req := canopen.NewRequest(frame, uint32(download.ResponseCobID))
-	c := &canopen.Client{Bus: bus, Timeout: time.Second * 2}
-	resp, err := c.Do(req)
+	c := &canopen.Client{Bus: bus, Timeout: transferTimeout(download.Timeout)}
+	resp, err := c.DoMinDurationContext(ctx, req, minDuration(download.Delay, 10*time.Millisecond))
 	if err != nil {
-		return err, segmentsPerBlock
+		return err, segmentsPerBlock, hasCRC
 	}
 
 	frame = resp.Frame
 	if isBlockTransfer {
 		segmentsPerBlock = int(frame.Data[4])
+
+		// sc = 1 (server supports CRC)
+		hasCRC = sdo.HasBit(frame.Data[0], 2)
 	}
 
 	scs := frame.Data[0] >> 5
@@ -82,23 +113,23 @@ func (download Download) doInitFrame(bus *can.Bus, isBlockTransfer bool) (error,
 		if !download.ObjectIndex.Compare(frame.ObjectIndex()) {
 			return canopen.TransferAbort{
 				AbortCode: sdo.GetAbortCodeBytes(frame),
-			}, segmentsPerBlock
+			}, segmentsPerBlock, hasCRC
 		}
 
 	} else if scs == 4 { // Abort
 		return canopen.TransferAbort{
 			AbortCode: sdo.GetAbortCodeBytes(frame),
-		}, segmentsPerBlock
+		}, segmentsPerBlock, hasCRC
 
 	} else {
 		return canopen.UnexpectedSCSResponse{
 			Expected:  3,
 			Actual:    scs,
 			AbortCode: sdo.GetAbortCodeBytes(frame),
-		}, segmentsPerBlock
+		}, segmentsPerBlock, hasCRC
 	}
 
-	return nil, segmentsPerBlock
+	return nil, segmentsPerBlock, hasCRC
 }
 
 // initFrame returns the initial frame of the download.
@@ -132,6 +163,9 @@ func (download Download) initFrame(isBlockTransfer bool) (frame canopen.Frame, e
 		if isBlockTransfer {
 			// Always indicate size in block transfer
 			fdata[0] = sdo.SetBit(fdata[0], 1)
+
+			// cc = 1 (client supports CRC)
+			fdata[0] = sdo.SetBit(fdata[0], 2)
 		} else {
 			// e = 0
 			// n = 0 (frame.Data contains the overall )
@@ -155,13 +189,13 @@ func (download Download) initFrame(isBlockTransfer bool) (frame canopen.Frame, e
 	return
 }
 
-func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
+func (download Download) doBlock(ctx context.Context, bus *can.Bus, segmentsPerBlock int, hasCRC bool) error {
 	index := 0
 	segmentIndex := 0
-	delay := 500 * time.Microsecond
+	delay := minDuration(download.Delay, 500*time.Microsecond)
 	retryDelay := 1 * time.Millisecond
 	frames := download.segmentFrames(true)
-	c := &canopen.Client{Bus: bus, Timeout: time.Second * 2}
+	c := &canopen.Client{Bus: bus, Timeout: transferTimeout(download.Timeout)}
 	for segmentIndex < len(frames) {
 		// Don't wait for the confirmation frame
 		var err error = nil
@@ -169,7 +203,7 @@ func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
 			frames[segmentIndex+index].Data[0] = getFirstByte(index, false, 7, true)
 			err = retry.Do(func() error {
 				return bus.PublishMinDuration(frames[segmentIndex+index].CANFrame(), delay)
-			}, retry.Attempts(10), retry.Delay(retryDelay))
+			}, retry.Attempts(10), retry.Delay(retryDelay), retry.Context(ctx))
 		}
 
 		// Wait for the confirmation frame
@@ -178,12 +212,16 @@ func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
 		err = retry.Do(func() error {
 			frames[segmentIndex+index].Data[0] = getFirstByte(index, segmentIndex+index+1 == len(frames), 7, true)
 			req := canopen.NewRequest(frames[segmentIndex+index], uint32(download.ResponseCobID))
-			resp, err1 = c.DoMinDuration(req, delay)
+			resp, err1 = c.DoMinDurationContext(ctx, req, delay)
 			return err1
-		}, retry.Attempts(5), retry.Delay(retryDelay))
+		}, retry.Attempts(5), retry.Delay(retryDelay), retry.Context(ctx), retry.LastErrorOnly(true))
 
+		// The server didn't confirm the block, DoBlockContext aborts the transfer if ctx is done
 		if err != nil {
-			break
+			if ctx.Err() == nil {
+				download.abort(bus, canopen.SDO_ERR_TIMEOUT)
+			}
+			return err
 		}
 
 		// Mask out the correct bits
@@ -194,15 +232,9 @@ func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
 			segmentsPerBlock = int(resp.Frame.Data[2])
 			ackSegment := int(resp.Frame.Data[1])
 
-			// If last segment (Everything is fine, move along please)
-			if segmentIndex+index+1 == len(frames) || ackSegment == segmentsPerBlock {
-				segmentIndex += ackSegment
-				index = 0
-
-				// Retry from the last acked segment
-			} else {
-				index = ackSegment
-			}
+			// Continue after the last acked segment, a new block starts with sequence number 1
+			segmentIndex += ackSegment
+			index = 0
 		} else {
 			return canopen.UnexpectedSCSResponse{
 				Expected:  5,
@@ -213,7 +245,7 @@ func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
 	}
 
 	// Send the end block
-	err := download.doBlockEnd(c)
+	err := download.doBlockEnd(ctx, c, hasCRC)
 	if err != nil {
 		return err
 	}
@@ -221,20 +253,30 @@ func (download Download) doBlock(bus *can.Bus, segmentsPerBlock int) error {
 	return nil
 }
 
-func (download Download) doBlockEnd(c *canopen.Client) error {
+func (download Download) doBlockEnd(ctx context.Context, c *canopen.Client, hasCRC bool) error {
 	fdata := make([]byte, 8)
 
 	// css = 6 (download init block request)
 	fdata[0] = byte(sdo.ClientBlockDownload << 5)
 
 	// n (Set the length of data in the last frame in the last segment)
-	fdata[0] |= uint8(7-(len(download.Data)%7)) << 2
+	// Without data the only segment contains no data, so all 7 bytes are unused
+	n := (7 - len(download.Data)%7) % 7
+	if len(download.Data) == 0 {
+		n = 7
+	}
+	fdata[0] |= uint8(n) << 2
 
 	// cs = 1 (indicate download end)
 	fdata[0] = sdo.SetBit(fdata[0], 0)
 
+	// CRC of the complete data, if client and server support it
+	if hasCRC {
+		binary.LittleEndian.PutUint16(fdata[1:3], sdo.CRC16(download.Data))
+	}
+
 	req := canopen.NewRequest(canopen.NewFrame(download.RequestCobID, fdata), uint32(download.ResponseCobID))
-	resp, err := c.DoMinDuration(req, 0)
+	resp, err := c.DoMinDurationContext(ctx, req, 0)
 
 	if err != nil {
 		return err
@@ -254,13 +296,13 @@ func (download Download) doBlockEnd(c *canopen.Client) error {
 	return nil
 }
 
-func (download Download) doSegments(bus *can.Bus) error {
+func (download Download) doSegments(ctx context.Context, bus *can.Bus) error {
 	frames := download.segmentFrames(false)
 
-	c := &canopen.Client{Bus: bus, Timeout: time.Second * 2}
+	c := &canopen.Client{Bus: bus, Timeout: transferTimeout(download.Timeout)}
 	for _, frame := range frames {
 		req := canopen.NewRequest(frame, uint32(download.ResponseCobID))
-		resp, err := c.DoMinDuration(req, 2*time.Millisecond)
+		resp, err := c.DoMinDurationContext(ctx, req, minDuration(download.Delay, 2*time.Millisecond))
 		if err != nil {
 			return err
 		}
@@ -316,6 +358,12 @@ func (download Download) segmentFrames(isBlockTransfer bool) (frames []canopen.F
 	return
 }
 
+// abort informs the server that the transfer is aborted
+func (download Download) abort(bus *can.Bus, errorCode canopen.SDOAbortCode) {
+	frame := canopen.NewFrame(download.RequestCobID, sdo.Pad(sdo.AbortData(errorCode, download.ObjectIndex), 8))
+	_ = bus.PublishMinDuration(frame.CANFrame(), 0)
+}
+
 func getFirstByte(i int, isLast bool, junkLength int, isBlockTransfer bool) byte {
 	firstByte := byte(0)
 	if !isBlockTransfer {
@@ -0,0 +1,52 @@
+package sdoClient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/canopen"
+	"testing"
+	"time"
+)
+
+func TestDownloadDoBlockUnconfirmed(t *testing.T) {
+	bus, server := newScriptedServer(t)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Download{
+			ObjectIndex:   canopen.NewObjectIndex(0x2000, 1),
+			Data:          []byte("0123456789"),
+			RequestCobID:  canopen.MessageTypeRSDO + 1,
+			ResponseCobID: canopen.MessageTypeTSDO + 1,
+			Timeout:       20 * time.Millisecond,
+		}.DoBlock(bus)
+	}()
+
+	server.expect(0xC6, 0x00, 0x20, 0x01, 10, 0, 0, 0)
+	server.respond(0xA4, 0x00, 0x20, 0x01, 127)
+
+	// The block is never confirmed, the client aborts the transfer instead of ending it
+	abort := append([]byte{0x80, 0x00, 0x20, 0x01}, binary.LittleEndian.AppendUint32(nil, uint32(canopen.SDO_ERR_TIMEOUT))...)
+	for {
+		frame, err := server.requests.Receive(time.Second)
+		if err != nil || frame.Data[0]&0xE1 == 0xC1 {
+			t.Log("Missing abort", frame.Data, err)
+			t.FailNow()
+		}
+
+		if frame.Data[0] == abort[0] {
+			server.requests.Close()
+			if !bytes.Equal(frame.Data, abort) {
+				t.Log("Unexpected abort", frame.Data, "expected", abort)
+				t.FailNow()
+			}
+			break
+		}
+	}
+
+	if err := <-errs; err == nil || errors.As(err, new(canopen.UnexpectedSCSResponse)) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
@@ -1,11 +1,11 @@
 package sdoClient
 
 import (
+	"context"
 	"encoding/binary"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
 	"github.com/FabianPetersen/canopen/sdo"
-	"strconv"
 
 	"bytes"
 	"time"
@@ -18,15 +18,39 @@ type Upload struct {
 
 	RequestCobID  uint16
 	ResponseCobID uint16
+
+	// BlockSize is the number of segments per block in a block upload (1-127).
+	// The maximum block size is used if not set.
+	BlockSize uint8
+	// Timeout is the time to wait for a response of the server, DefaultTimeout is used if not set
+	Timeout time.Duration
+	// Delay is the minimum duration of every frame sent to the server, the defaults of the transfer are used if not set
+	Delay time.Duration
 }
 
 func (upload Upload) Do(bus *can.Bus) ([]byte, error) {
+	return upload.DoContext(context.Background(), bus)
+}
+
+// DoContext reads the data with an expedited or segmented upload until ctx is done.
+// If ctx is done during the transfer, the transfer is aborted and the error wraps ctx.Err().
+func (upload Upload) DoContext(ctx context.Context, bus *can.Bus) ([]byte, error) {
 	// Do not allow multiple messages for the same device
-	key := strconv.Itoa(int(upload.RequestCobID))
-	canopen.Lock.Lock(key)
-	defer canopen.Lock.Unlock(key)
+	if err := lock(ctx, upload.RequestCobID); err != nil {
+		return nil, err
+	}
+	defer unlock(upload.RequestCobID)
+
+	data, err := upload.do(ctx, bus)
+	if err != nil && ctx.Err() != nil {
+		abortContext(ctx, bus, upload.RequestCobID, upload.ObjectIndex)
+	}
 
-	c := &canopen.Client{Bus: bus, Timeout: time.Second * 2}
+	return data, err
+}
+
+func (upload Upload) do(ctx context.Context, bus *can.Bus) ([]byte, error) {
+	c := &canopen.Client{Bus: bus, Timeout: transferTimeout(upload.Timeout)}
 	// Initiate
 	frame := canopen.Frame{
 		CobID: upload.RequestCobID,
@@ -39,7 +63,7 @@ func (upload Upload) Do(bus *can.Bus) ([]byte, error) {
 	}
 
 	req := canopen.NewRequest(frame, uint32(upload.ResponseCobID))
-	resp, err := c.Do(req)
+	resp, err := c.DoMinDurationContext(ctx, req, minDuration(upload.Delay, 10*time.Millisecond))
 	if err != nil {
 		return nil, err
 	}
@@ -101,7 +125,7 @@ func (upload Upload) Do(bus *can.Bus) ([]byte, error) {
 		}
 
 		req = canopen.NewRequest(frame, uint32(upload.ResponseCobID))
-		resp, err = c.DoMinDuration(req, 2*time.Millisecond)
+		resp, err = c.DoMinDurationContext(ctx, req, minDuration(upload.Delay, 2*time.Millisecond))
 		if err != nil {
 			return nil, err
 		}
@@ -138,3 +162,220 @@ func (upload Upload) Do(bus *can.Bus) ([]byte, error) {
 
 	return buf.Bytes(), nil
 }
+
+// DoBlock reads the data using an SDO block upload, which transfers up to 127 segments
+// before waiting for a confirmation. The CRC of the data is verified if the server supports it.
+func (upload Upload) DoBlock(bus *can.Bus) ([]byte, error) {
+	return upload.DoBlockContext(context.Background(), bus)
+}
+
+// DoBlockContext reads the data using an SDO block upload until ctx is done.
+// If ctx is done during the transfer, the transfer is aborted and the error wraps ctx.Err().
+func (upload Upload) DoBlockContext(ctx context.Context, bus *can.Bus) ([]byte, error) {
+	// Do not allow multiple messages for the same device
+	if err := lock(ctx, upload.RequestCobID); err != nil {
+		return nil, err
+	}
+	defer unlock(upload.RequestCobID)
+
+	blockSize := upload.BlockSize
+	if blockSize == 0 || blockSize > sdo.MaxBlockSize {
+		blockSize = sdo.MaxBlockSize
+	}
+
+	// The segments are sent without a request, subscribe before the transfer is initiated
+	sub := canopen.Subscribe(bus, upload.ResponseCobID, sdo.MaxBlockSize+1)
+	defer sub.Close()
+
+	// ccs = 5, cc = 1 (client supports CRC), cs = 0 (initiate)
+	// pst = 0 (no protocol switch)
+	frame, err := upload.publishAndReceive(ctx, bus, sub, []byte{
+		byte(sdo.ClientBlockUpload<<5) | 1<<2 | sdo.BlockInitiate,
+		upload.ObjectIndex.Index.B0, upload.ObjectIndex.Index.B1,
+		upload.ObjectIndex.SubIndex,
+		blockSize, 0x0, 0x0, 0x0,
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			upload.abortReceive(ctx, bus)
+		}
+		return nil, err
+	}
+
+	if err := upload.checkBlockResponse(bus, frame, sdo.BlockInitiate); err != nil {
+		return nil, err
+	}
+
+	// Check if this is the correct response for the requested message
+	if !upload.ObjectIndex.Compare(frame.ObjectIndex()) {
+		upload.abort(bus, canopen.SDO_ERR_COMMAND)
+		return nil, canopen.TransferAbort{}
+	}
+
+	hasCRC := sdo.HasBit(frame.Data[0], 2)
+	hasSize := sdo.HasBit(frame.Data[0], 1)
+	total := binary.LittleEndian.Uint32(frame.Data[4:8])
+
+	// ccs = 5, cs = 3 (start upload)
+	if err := upload.publish(bus, []byte{byte(sdo.ClientBlockUpload<<5) | sdo.BlockStartUpload}); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for {
+		data, ackSeq, isLast, err := upload.receiveBlock(ctx, bus, sub, blockSize)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(data)
+
+		// Check if the server sends more data than announced (including the unused bytes of the last segment)
+		if hasSize && buf.Len() > int(total)+6 {
+			upload.abort(bus, canopen.SDO_ERR_BLOCK_SEQUENCE)
+			return nil, canopen.UnexpectedResponseLength{
+				Expected: int(total),
+				Actual:   buf.Len(),
+			}
+		}
+
+		// ccs = 5, cs = 2 (block ack), ackseq, blksize
+		// The server retransmits all segments after ackseq in the next block
+		if err := upload.publish(bus, []byte{byte(sdo.ClientBlockUpload<<5) | sdo.BlockAck, ackSeq, blockSize}); err != nil {
+			return nil, err
+		}
+
+		if isLast {
+			break
+		}
+	}
+
+	frame, err = receive(ctx, sub, transferTimeout(upload.Timeout))
+	if err != nil {
+		upload.abortReceive(ctx, bus)
+		return nil, err
+	}
+
+	if err := upload.checkBlockResponse(bus, frame, sdo.BlockEnd); err != nil {
+		return nil, err
+	}
+
+	// n = number of bytes in the last segment that do not contain data
+	n := int((frame.Data[0] >> 2) & 0x7)
+	if n > buf.Len() {
+		upload.abort(bus, canopen.SDO_ERR_GENERAL)
+		return nil, canopen.UnexpectedResponseLength{
+			Expected: int(total),
+			Actual:   buf.Len() - n,
+		}
+	}
+	data := buf.Bytes()[:buf.Len()-n]
+
+	if hasSize && len(data) != int(total) {
+		upload.abort(bus, canopen.SDO_ERR_GENERAL)
+		return nil, canopen.UnexpectedResponseLength{
+			Expected: int(total),
+			Actual:   len(data),
+		}
+	}
+
+	if hasCRC && binary.LittleEndian.Uint16(frame.Data[1:3]) != sdo.CRC16(data) {
+		upload.abort(bus, canopen.SDO_ERR_BLOCK_CRC)
+		return nil, canopen.TransferAbort{
+			AbortCode: binary.LittleEndian.AppendUint32([]byte{}, uint32(canopen.SDO_ERR_BLOCK_CRC)),
+		}
+	}
+
+	// ccs = 5, cs = 1 (end)
+	if err := upload.publish(bus, []byte{byte(sdo.ClientBlockUpload<<5) | sdo.BlockEnd}); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// receiveBlock receives the segments of one block.
+// It returns the data of all segments up to the last segment received in sequence.
+func (upload Upload) receiveBlock(ctx context.Context, bus *can.Bus, sub *canopen.Subscription, blockSize uint8) ([]byte, uint8, bool, error) {
+	var buf bytes.Buffer
+	var ackSeq uint8
+	for {
+		frame, err := receive(ctx, sub, transferTimeout(upload.Timeout))
+		if err != nil {
+			upload.abortReceive(ctx, bus)
+			return nil, ackSeq, false, err
+		}
+
+		// Sequence numbers start at 1, a header of 0x80 is an abort
+		if frame.Data[0] == byte(sdo.AbortTransfer<<5) {
+			return nil, ackSeq, false, canopen.TransferAbort{
+				AbortCode: sdo.GetAbortCodeBytes(frame),
+			}
+		}
+
+		// c = 1 (no more segments)
+		isLast := sdo.HasBit(frame.Data[0], 7)
+		seq := frame.Data[0] & 0x7F
+
+		// Segments after a missing segment are dropped and retransmitted by the server
+		if seq == ackSeq+1 {
+			ackSeq = seq
+			buf.Write(frame.Data[1:8])
+		}
+
+		if isLast || seq >= blockSize {
+			return buf.Bytes(), ackSeq, isLast && seq == ackSeq, nil
+		}
+	}
+}
+
+func (upload Upload) checkBlockResponse(bus *can.Bus, frame canopen.Frame, ss byte) error {
+	scs := sdo.ServerCommandSpecifier(frame.Data[0] >> 5)
+	if scs == sdo.AbortTransfer {
+		return canopen.TransferAbort{
+			AbortCode: sdo.GetAbortCodeBytes(frame),
+		}
+	}
+
+	// ss is stored in bit 0, bit 1 is the s flag of the initiate response
+	if scs != sdo.ServerBlockUpload || frame.Data[0]&0x1 != ss {
+		upload.abort(bus, canopen.SDO_ERR_COMMAND)
+		return canopen.UnexpectedSCSResponse{
+			Expected:  uint8(sdo.ServerBlockUpload),
+			Actual:    uint8(scs),
+			AbortCode: sdo.GetAbortCodeBytes(frame),
+		}
+	}
+
+	return nil
+}
+
+func (upload Upload) publishAndReceive(ctx context.Context, bus *can.Bus, sub *canopen.Subscription, data []byte) (canopen.Frame, error) {
+	if err := upload.publish(bus, data); err != nil {
+		return canopen.Frame{}, err
+	}
+
+	return receive(ctx, sub, transferTimeout(upload.Timeout))
+}
+
+// publish sends a frame of a block upload, the frames are not delayed unless a delay is set
+func (upload Upload) publish(bus *can.Bus, data []byte) error {
+	// CiA301 Standard expects all (8) bytes to be sent
+	frame := canopen.NewFrame(upload.RequestCobID, sdo.Pad(data, 8))
+	return bus.PublishMinDuration(frame.CANFrame(), minDuration(upload.Delay, 0))
+}
+
+// abort informs the server that the transfer is aborted
+func (upload Upload) abort(bus *can.Bus, errorCode canopen.SDOAbortCode) {
+	_ = upload.publish(bus, sdo.AbortData(errorCode, upload.ObjectIndex))
+}
+
+// abortReceive informs the server that no frame was received, with the same abort code as abortContext if ctx is done
+func (upload Upload) abortReceive(ctx context.Context, bus *can.Bus) {
+	if ctx.Err() != nil {
+		abortContext(ctx, bus, upload.RequestCobID, upload.ObjectIndex)
+		return
+	}
+
+	upload.abort(bus, canopen.SDO_ERR_TIMEOUT)
+}
@@ -0,0 +1,149 @@
+package sdoClient
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"net"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers the requests of a client with frames defined by the test
+type scriptedServer struct {
+	t        *testing.T
+	bus      *can.Bus
+	requests *canopen.Subscription
+}
+
+func newScriptedServer(t *testing.T) (*can.Bus, *scriptedServer) {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	go serverBus.ConnectAndPublish()
+	t.Cleanup(func() {
+		clientBus.Disconnect()
+		serverBus.Disconnect()
+	})
+
+	requests := canopen.Subscribe(serverBus, canopen.MessageTypeRSDO+1, 20)
+	t.Cleanup(requests.Close)
+
+	return clientBus, &scriptedServer{t: t, bus: serverBus, requests: requests}
+}
+
+// expect receives the next request and compares its first bytes
+func (server *scriptedServer) expect(expected ...byte) canopen.Frame {
+	frame, err := server.requests.Receive(time.Second)
+	if err != nil || !bytes.Equal(frame.Data[:len(expected)], expected) {
+		server.t.Log("Unexpected request", frame.Data, err, "expected", expected)
+		server.t.FailNow()
+	}
+
+	return frame
+}
+
+func (server *scriptedServer) respond(data ...byte) {
+	_ = server.bus.PublishMinDuration(canopen.NewFrame(canopen.MessageTypeTSDO+1, sdo.Pad(data, 8)).CANFrame(), 0)
+}
+
+func TestUploadDoBlock(t *testing.T) {
+	bus, server := newScriptedServer(t)
+
+	// 20 bytes are 3 segments, the last segment has 1 unused byte
+	data := []byte("abcdefghijklmnopqrst")
+	upload := Upload{
+		ObjectIndex:   canopen.NewObjectIndex(0x2000, 1),
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		BlockSize:     2,
+		Timeout:       500 * time.Millisecond,
+	}
+
+	for _, crc := range []uint16{sdo.CRC16(data), sdo.CRC16(data) ^ 0xFFFF} {
+		type result struct {
+			data []byte
+			err  error
+		}
+		results := make(chan result, 1)
+		go func() {
+			data, err := upload.DoBlock(bus)
+			results <- result{data, err}
+		}()
+
+		// ccs = 5, cc = 1, cs = 0, blksize = 2
+		server.expect(0xA4, 0x00, 0x20, 0x01, 2)
+		// scs = 6, sc = 1, s = 1, ss = 0, size = 20
+		server.respond(0xC6, 0x00, 0x20, 0x01, 20, 0, 0, 0)
+		// ccs = 5, cs = 3 (start upload)
+		server.expect(0xA3)
+
+		server.respond(append([]byte{0x01}, data[0:7]...)...)
+		server.respond(append([]byte{0x02}, data[7:14]...)...)
+		// ccs = 5, cs = 2 (block ack), ackseq = 2, blksize = 2
+		server.expect(0xA2, 2, 2)
+
+		// c = 1, seqno = 1
+		server.respond(append([]byte{0x81}, data[14:20]...)...)
+		server.expect(0xA2, 1, 2)
+
+		// scs = 6, n = 1, ss = 1 (end), crc
+		server.respond(append([]byte{0xC5}, binary.LittleEndian.AppendUint16(nil, crc)...)...)
+
+		if crc == sdo.CRC16(data) {
+			// ccs = 5, cs = 1 (end)
+			server.expect(0xA1)
+			if r := <-results; r.err != nil || !bytes.Equal(r.data, data) {
+				t.Log("Unexpected data", r.data, r.err)
+				t.FailNow()
+			}
+			continue
+		}
+
+		// The client aborts the transfer if the CRC doesn't match
+		server.expect(append([]byte{0x80, 0x00, 0x20, 0x01}, binary.LittleEndian.AppendUint32(nil, uint32(canopen.SDO_ERR_BLOCK_CRC))...)...)
+		var abort canopen.TransferAbort
+		if r := <-results; !errors.As(r.err, &abort) || abort.Code() != canopen.SDO_ERR_BLOCK_CRC {
+			t.Log("Unexpected error", r.err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestUploadDoBlockCancel(t *testing.T) {
+	bus, server := newScriptedServer(t)
+
+	upload := Upload{
+		ObjectIndex:   canopen.NewObjectIndex(0x2000, 1),
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		BlockSize:     2,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := upload.DoBlockContext(ctx, bus)
+		errs <- err
+	}()
+
+	server.expect(0xA4, 0x00, 0x20, 0x01, 2)
+	server.respond(0xC6, 0x00, 0x20, 0x01, 20, 0, 0, 0)
+	server.expect(0xA3)
+	server.respond(0x01, 'a', 'b', 'c', 'd', 'e', 'f', 'g')
+
+	// Cancelling while the segments are received aborts with the same code as the initiate
+	cancel()
+	server.expect(append([]byte{0x80, 0x00, 0x20, 0x01}, binary.LittleEndian.AppendUint32(nil, uint32(canopen.SDO_ERR_GENERAL))...)...)
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Log("Unexpected error", err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,217 @@
+package sdoClient
+
+import (
+	"context"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"time"
+)
+
+// ReadValue reads the value of an object with the data type until ctx is done.
+// The length of the value is validated against the data type, see sdo.Decode.
+func ReadValue[T sdo.Value](ctx context.Context, client *Client, objectIndex canopen.ObjectIndex, datatype sdo.SDODataType) (T, error) {
+	data, err := client.ReadContext(ctx, objectIndex)
+	if err != nil {
+		var value T
+		return value, err
+	}
+
+	value, err := sdo.Decode[T](datatype, data)
+	if err != nil {
+		return value, fmt.Errorf("read %X sub index %d: %w", objectIndex.Index.Index(), objectIndex.SubIndex, err)
+	}
+
+	return value, nil
+}
+
+// WriteValue writes the value of an object with the data type until ctx is done.
+// The value is encoded before the transfer, see sdo.Encode.
+func WriteValue[T sdo.Value](ctx context.Context, client *Client, objectIndex canopen.ObjectIndex, datatype sdo.SDODataType, value T) error {
+	data, err := sdo.Encode(datatype, value)
+	if err != nil {
+		return fmt.Errorf("write %X sub index %d: %w", objectIndex.Index.Index(), objectIndex.SubIndex, err)
+	}
+
+	return client.WriteContext(ctx, objectIndex, data)
+}
+
+// ReadBool reads a BOOLEAN object.
+func (client *Client) ReadBool(objectIndex canopen.ObjectIndex) (bool, error) {
+	return ReadValue[bool](context.Background(), client, objectIndex, sdo.DATA_TYPE_BOOLEAN)
+}
+
+// ReadUint8 reads an UNSIGNED_8 object.
+func (client *Client) ReadUint8(objectIndex canopen.ObjectIndex) (uint8, error) {
+	return ReadValue[uint8](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_8)
+}
+
+// ReadUint16 reads an UNSIGNED_16 object.
+func (client *Client) ReadUint16(objectIndex canopen.ObjectIndex) (uint16, error) {
+	return ReadValue[uint16](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_16)
+}
+
+// ReadUint24 reads an UNSIGNED_24 object.
+func (client *Client) ReadUint24(objectIndex canopen.ObjectIndex) (uint32, error) {
+	return ReadValue[uint32](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_24)
+}
+
+// ReadUint32 reads an UNSIGNED_32 object.
+func (client *Client) ReadUint32(objectIndex canopen.ObjectIndex) (uint32, error) {
+	return ReadValue[uint32](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_32)
+}
+
+// ReadUint64 reads an UNSIGNED_64 object.
+func (client *Client) ReadUint64(objectIndex canopen.ObjectIndex) (uint64, error) {
+	return ReadValue[uint64](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_64)
+}
+
+// ReadInt8 reads an INTEGER_8 object.
+func (client *Client) ReadInt8(objectIndex canopen.ObjectIndex) (int8, error) {
+	return ReadValue[int8](context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_8)
+}
+
+// ReadInt16 reads an INTEGER_16 object.
+func (client *Client) ReadInt16(objectIndex canopen.ObjectIndex) (int16, error) {
+	return ReadValue[int16](context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_16)
+}
+
+// ReadInt24 reads an INTEGER_24 object.
+func (client *Client) ReadInt24(objectIndex canopen.ObjectIndex) (int32, error) {
+	return ReadValue[int32](context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_24)
+}
+
+// ReadInt32 reads an INTEGER_32 object.
+func (client *Client) ReadInt32(objectIndex canopen.ObjectIndex) (int32, error) {
+	return ReadValue[int32](context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_32)
+}
+
+// ReadInt64 reads an INTEGER_64 object.
+func (client *Client) ReadInt64(objectIndex canopen.ObjectIndex) (int64, error) {
+	return ReadValue[int64](context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_64)
+}
+
+// ReadFloat32 reads a REAL_32 object.
+func (client *Client) ReadFloat32(objectIndex canopen.ObjectIndex) (float32, error) {
+	return ReadValue[float32](context.Background(), client, objectIndex, sdo.DATA_TYPE_REAL_32)
+}
+
+// ReadFloat64 reads a REAL_64 object.
+func (client *Client) ReadFloat64(objectIndex canopen.ObjectIndex) (float64, error) {
+	return ReadValue[float64](context.Background(), client, objectIndex, sdo.DATA_TYPE_REAL_64)
+}
+
+// ReadVisibleString reads a VISIBLE_STRING object, trailing null characters are removed.
+func (client *Client) ReadVisibleString(objectIndex canopen.ObjectIndex) (string, error) {
+	return ReadValue[string](context.Background(), client, objectIndex, sdo.DATA_TYPE_VISIBLE_STRING)
+}
+
+// ReadOctetString reads an OCTET_STRING object.
+func (client *Client) ReadOctetString(objectIndex canopen.ObjectIndex) ([]byte, error) {
+	return ReadValue[[]byte](context.Background(), client, objectIndex, sdo.DATA_TYPE_OCTET_STRING)
+}
+
+// ReadUnicodeString reads a UNICODE_STRING object encoded as UTF-16.
+func (client *Client) ReadUnicodeString(objectIndex canopen.ObjectIndex) (string, error) {
+	return ReadValue[string](context.Background(), client, objectIndex, sdo.DATA_TYPE_UNICODE_STRING)
+}
+
+// ReadTimeOfDay reads a TIME_OF_DAY object.
+func (client *Client) ReadTimeOfDay(objectIndex canopen.ObjectIndex) (time.Time, error) {
+	return ReadValue[time.Time](context.Background(), client, objectIndex, sdo.DATA_TYPE_TIME_OF_DAY)
+}
+
+// ReadTimeDifference reads a TIME_DIFFERENCE object.
+func (client *Client) ReadTimeDifference(objectIndex canopen.ObjectIndex) (time.Duration, error) {
+	return ReadValue[time.Duration](context.Background(), client, objectIndex, sdo.DATA_TYPE_TIME_DIFFERENCE)
+}
+
+// WriteBool writes a BOOLEAN object.
+func (client *Client) WriteBool(objectIndex canopen.ObjectIndex, value bool) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_BOOLEAN, value)
+}
+
+// WriteUint8 writes an UNSIGNED_8 object.
+func (client *Client) WriteUint8(objectIndex canopen.ObjectIndex, value uint8) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_8, value)
+}
+
+// WriteUint16 writes an UNSIGNED_16 object.
+func (client *Client) WriteUint16(objectIndex canopen.ObjectIndex, value uint16) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_16, value)
+}
+
+// WriteUint24 writes an UNSIGNED_24 object, values above 0xFFFFFF are rejected.
+func (client *Client) WriteUint24(objectIndex canopen.ObjectIndex, value uint32) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_24, value)
+}
+
+// WriteUint32 writes an UNSIGNED_32 object.
+func (client *Client) WriteUint32(objectIndex canopen.ObjectIndex, value uint32) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_32, value)
+}
+
+// WriteUint64 writes an UNSIGNED_64 object.
+func (client *Client) WriteUint64(objectIndex canopen.ObjectIndex, value uint64) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNSIGNED_64, value)
+}
+
+// WriteInt8 writes an INTEGER_8 object.
+func (client *Client) WriteInt8(objectIndex canopen.ObjectIndex, value int8) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_8, value)
+}
+
+// WriteInt16 writes an INTEGER_16 object.
+func (client *Client) WriteInt16(objectIndex canopen.ObjectIndex, value int16) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_16, value)
+}
+
+// WriteInt24 writes an INTEGER_24 object, values outside of -0x800000 to 0x7FFFFF are rejected.
+func (client *Client) WriteInt24(objectIndex canopen.ObjectIndex, value int32) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_24, value)
+}
+
+// WriteInt32 writes an INTEGER_32 object.
+func (client *Client) WriteInt32(objectIndex canopen.ObjectIndex, value int32) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_32, value)
+}
+
+// WriteInt64 writes an INTEGER_64 object.
+func (client *Client) WriteInt64(objectIndex canopen.ObjectIndex, value int64) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_INTEGER_64, value)
+}
+
+// WriteFloat32 writes a REAL_32 object.
+func (client *Client) WriteFloat32(objectIndex canopen.ObjectIndex, value float32) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_REAL_32, value)
+}
+
+// WriteFloat64 writes a REAL_64 object.
+func (client *Client) WriteFloat64(objectIndex canopen.ObjectIndex, value float64) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_REAL_64, value)
+}
+
+// WriteVisibleString writes a VISIBLE_STRING object.
+func (client *Client) WriteVisibleString(objectIndex canopen.ObjectIndex, value string) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_VISIBLE_STRING, value)
+}
+
+// WriteOctetString writes an OCTET_STRING object.
+func (client *Client) WriteOctetString(objectIndex canopen.ObjectIndex, value []byte) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_OCTET_STRING, value)
+}
+
+// WriteUnicodeString writes a UNICODE_STRING object encoded as UTF-16.
+func (client *Client) WriteUnicodeString(objectIndex canopen.ObjectIndex, value string) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_UNICODE_STRING, value)
+}
+
+// WriteTimeOfDay writes a TIME_OF_DAY object.
+func (client *Client) WriteTimeOfDay(objectIndex canopen.ObjectIndex, value time.Time) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_TIME_OF_DAY, value)
+}
+
+// WriteTimeDifference writes a TIME_DIFFERENCE object.
+func (client *Client) WriteTimeDifference(objectIndex canopen.ObjectIndex, value time.Duration) error {
+	return WriteValue(context.Background(), client, objectIndex, sdo.DATA_TYPE_TIME_DIFFERENCE, value)
+}
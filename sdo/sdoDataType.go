@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
 	"golang.org/x/exp/slices"
 	"log"
 	"math"
@@ -15,6 +17,12 @@ import (
 
 type SDODataType byte
 
+// dateFormat is the string format of TIME_OF_DAY and TIME_DIFFERENCE values
+const dateFormat = "2006-01-02 15:04:05.000"
+
+// timeDifferenceRefDate is the date TIME_DIFFERENCE values are formatted relative to
+var timeDifferenceRefDate = time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+
 // SDOOBJECT_TYPE_BOOLEAN
 const (
 	DATA_TYPE_BOOLEAN                     SDODataType = 0x01
@@ -62,6 +70,31 @@ func IsReversed(datatype SDODataType) bool {
 	}, datatype)
 }
 
+// DataTypeSize returns the number of bytes of a data type.
+// 0 is returned for data types without a fixed size (strings, domains and records).
+func DataTypeSize(datatype SDODataType) int {
+	switch datatype {
+	case DATA_TYPE_BOOLEAN, DATA_TYPE_INTEGER_8, DATA_TYPE_UNSIGNED_8:
+		return 1
+	case DATA_TYPE_INTEGER_16, DATA_TYPE_UNSIGNED_16:
+		return 2
+	case DATA_TYPE_INTEGER_24, DATA_TYPE_UNSIGNED_24:
+		return 3
+	case DATA_TYPE_INTEGER_32, DATA_TYPE_UNSIGNED_32, DATA_TYPE_REAL_32:
+		return 4
+	case DATA_TYPE_INTEGER_40, DATA_TYPE_UNSIGNED_40:
+		return 5
+	case DATA_TYPE_INTEGER_48, DATA_TYPE_UNSIGNED_48, DATA_TYPE_TIME_OF_DAY, DATA_TYPE_TIME_DIFFERENCE:
+		return 6
+	case DATA_TYPE_INTEGER_56, DATA_TYPE_UNSIGNED_56:
+		return 7
+	case DATA_TYPE_INTEGER_64, DATA_TYPE_UNSIGNED_64, DATA_TYPE_REAL_64:
+		return 8
+	}
+
+	return 0
+}
+
 func DataTypeToByte(datatype SDODataType, data string) ([]byte, bool) {
 	var defaultValue []byte
 	var err error
@@ -157,14 +190,10 @@ func DataTypeToByte(datatype SDODataType, data string) ([]byte, bool) {
 		defaultValue = []byte(data)
 
 	case DATA_TYPE_TIME_OF_DAY:
-		loc, _ := time.LoadLocation("UTC")
-		offset := time.Date(1984, 1, 1, 0, 0, 0, 0, loc)
-		defaultValue, err = ParseDateString(data, offset)
+		defaultValue, err = ParseDateString(data, canopen.RefDate)
 
 	case DATA_TYPE_TIME_DIFFERENCE:
-		loc, _ := time.LoadLocation("UTC")
-		offset := time.Date(0, 1, 1, 0, 0, 0, 0, loc)
-		defaultValue, err = ParseDateString(data, offset)
+		defaultValue, err = ParseDateString(data, timeDifferenceRefDate)
 	}
 
 	if err != nil {
@@ -219,14 +248,10 @@ func ByteToDataType(datatype SDODataType, data []byte) (string, bool) {
 		return string(start), false
 
 	case DATA_TYPE_TIME_OF_DAY:
-		loc, _ := time.LoadLocation("UTC")
-		date := time.Date(1984, 1, 1, 0, 0, 0, 0, loc)
-		return ParseDate(data, date)
+		return ParseDate(data, canopen.RefDate)
 
 	case DATA_TYPE_TIME_DIFFERENCE:
-		loc, _ := time.LoadLocation("UTC")
-		date := time.Date(0, 1, 1, 0, 0, 0, 0, loc)
-		return ParseDate(data, date)
+		return ParseDate(data, timeDifferenceRefDate)
 
 	default:
 		return "", false
@@ -292,35 +317,30 @@ func ParseInt(b []byte) (int64, error) {
 	return n, nil
 }
 
+// ParseDate formats a TIME_OF_DAY or TIME_DIFFERENCE value as the time since date.
 func ParseDate(data []byte, date time.Time) (string, bool) {
-	ms := binary.LittleEndian.Uint32([]byte{data[0], data[1], data[2], data[3] & 0xf0})
-	days := binary.LittleEndian.Uint16([]byte{data[4], data[5]})
+	difference, err := canopen.DecodeTimeDifference(data)
+	if err != nil {
+		log.Println("ERROR when converting: ", err)
+		return "", true
+	}
 
-	date.Add(time.Duration(days*24) * time.Hour)
-	date.Add(time.Duration(ms) * time.Millisecond)
-	return date.Format("2006-01-02 15:04:05.000"), false
+	return date.Add(difference).Format(dateFormat), false
 }
 
+// ParseDateString returns the TIME_OF_DAY or TIME_DIFFERENCE value of a formatted date,
+// the days and milliseconds are counted from offset.
 func ParseDateString(data string, offset time.Time) ([]byte, error) {
-	date, err := time.Parse("2006-01-02 15:04:05.000", data)
-
-	// Days / ms has 1984 as starting point
-	date.Sub(offset)
-
-	ms := date.UnixNano() / 1000
-	days := uint64(ms / int64(time.Hour*24))
-	ms %= int64(time.Hour * 24)
-
-	msBytes := make([]byte, 4)
-	daysBytes := make([]byte, 2)
-	binary.LittleEndian.PutUint32(msBytes, uint32(ms))
-	binary.LittleEndian.PutUint16(daysBytes, uint16(days))
+	date, err := time.Parse(dateFormat, data)
+	if err != nil {
+		return nil, err
+	}
 
-	outputBytes := []byte{}
-	outputBytes = append(outputBytes, msBytes...)
-	outputBytes = append(outputBytes, daysBytes...)
+	if date.Before(offset) {
+		return nil, fmt.Errorf("date %s is before %s", data, offset.Format(dateFormat))
+	}
 
-	return outputBytes, err
+	return canopen.EncodeTimeDifference(date.Sub(offset))
 }
 
 func reverse(s interface{}) {
@@ -103,3 +103,121 @@ func (server *Server) ordinaryDownload(frame canopen.Frame) {
 		server.publishError(downloadError, objectIndex)
 	}
 }
+
+func (server *Server) handleBlockDownload(frame canopen.Frame) {
+	objectIndex := frame.ObjectIndex()
+
+	// cc = 1 (client supports CRC)
+	hasCRC := sdo.HasBit(frame.Data[0], 2)
+
+	// s = 1, data 4-7 contains the number of bytes to be downloaded
+	hasSize := sdo.HasBit(frame.Data[0], 1)
+	size := int(binary.LittleEndian.Uint32(frame.Data[4:]))
+
+	sub := server.beginBlockTransfer()
+	defer server.endBlockTransfer(sub)
+
+	// Accept the request, scs = 5, sc (server supports CRC), ss = 0 (initiate), blksize
+	headerByte := byte(sdo.ServerBlockDownload<<5) | sdo.BlockInitiate
+	if hasCRC {
+		headerByte = sdo.SetBit(headerByte, 2)
+	}
+	initData := append(append([]byte{headerByte}, objectIndex.Bytes()...), sdo.MaxBlockSize)
+	if err := server.publish(initData); err != nil {
+		return
+	}
+
+	completeData := []byte{}
+	for isLast := false; !isLast; {
+		var ackSeq uint8
+		for {
+			resp, err := sub.Receive(server.client.Timeout)
+			if err != nil {
+				// Abort the request (no response)
+				server.publishError(canopen.SDO_ERR_TIMEOUT, objectIndex)
+				return
+			}
+
+			if isAbort(resp) {
+				return
+			}
+
+			// c = 1 (no more segments), seqno
+			c := sdo.HasBit(resp.Data[0], 7)
+			seq := resp.Data[0] & 0x7F
+
+			// Segments after a missing segment are dropped and retransmitted by the client
+			if seq == ackSeq+1 {
+				ackSeq = seq
+				isLast = c
+				completeData = append(completeData, resp.Data[1:8]...)
+			}
+
+			if c || seq >= sdo.MaxBlockSize {
+				break
+			}
+		}
+
+		// Check that the client does not send more data than announced (including the unused bytes of the last segment),
+		// without data the client sends one segment without data
+		if hasSize && len(completeData) > size+6 && (size > 0 || len(completeData) > 7) {
+			server.publishError(canopen.SDO_ERR_DATATYPE_HIGH, objectIndex)
+			return
+		}
+
+		// Confirm the block, scs = 5, ss = 2 (block ack), ackseq, blksize
+		if err := server.publish([]byte{byte(sdo.ServerBlockDownload<<5) | sdo.BlockAck, ackSeq, sdo.MaxBlockSize}); err != nil {
+			return
+		}
+	}
+
+	// Wait for the end of the transfer
+	resp, err := sub.Receive(server.client.Timeout)
+	if err != nil {
+		server.publishError(canopen.SDO_ERR_TIMEOUT, objectIndex)
+		return
+	}
+
+	if isAbort(resp) {
+		return
+	}
+
+	// ccs = 6, cs = 1 (end)
+	ccs := sdo.ClientCommandSpecifier(resp.Data[0] >> 5)
+	if ccs != sdo.ClientBlockDownload || resp.Data[0]&0x1 != sdo.BlockEnd {
+		server.publishError(canopen.SDO_ERR_COMMAND, objectIndex)
+		return
+	}
+
+	// n = number of bytes in the last segment that do not contain data
+	n := int((resp.Data[0] >> 2) & 0x7)
+	if n > len(completeData) {
+		server.publishError(canopen.SDO_ERR_DATATYPE_LOW, objectIndex)
+		return
+	}
+	completeData = completeData[:len(completeData)-n]
+
+	if hasSize && len(completeData) != size {
+		server.publishError(canopen.SDO_ERR_DATATYPE, objectIndex)
+		return
+	}
+
+	// Verify the CRC of the complete data
+	if hasCRC && binary.LittleEndian.Uint16(resp.Data[1:3]) != sdo.CRC16(completeData) {
+		server.publishError(canopen.SDO_ERR_BLOCK_CRC, objectIndex)
+		return
+	}
+
+	// Accept new requests before the client is able to send them
+	server.blockTransfer.Store(false)
+
+	// Process the data
+	downloadError := server.Download(objectIndex, completeData)
+
+	// Sent the response, scs = 5, ss = 1 (end)
+	if downloadError == canopen.NO_ERROR {
+		_ = server.publish([]byte{byte(sdo.ServerBlockDownload<<5) | sdo.BlockEnd})
+	} else {
+		server.publishError(downloadError, objectIndex)
+	}
+}
@@ -1,23 +1,38 @@
 package sdoServer
 
 import (
-	"encoding/binary"
 	"github.com/FabianPetersen/can"
 	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/od"
 	"github.com/FabianPetersen/canopen/sdo"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// segmentDelay is the minimum duration between two segments of a block
+const segmentDelay = 500 * time.Microsecond
+
 type Server struct {
-	bus              *can.Bus
-	client           *canopen.Client
-	messageQueue     chan canopen.Frame
+	bus           *can.Bus
+	client        *canopen.Client
+	messageQueue  chan canopen.Frame
+	blockTransfer atomic.Bool
+
+	// lock protects the active node id and the COB-IDs derived from it
+	lock             sync.RWMutex
+	nodeID           uint8
 	clientRequestId  uint16
 	serverResponseId uint16
 
+	// NodeId is the node id the server starts listening with, the server doesn't change it.
+	// Use NodeID and SetNodeID to access the active node id while the server is listening.
 	NodeId   uint8
 	Upload   func(canopen.ObjectIndex) ([]byte, canopen.SDOAbortCode)
 	Download func(canopen.ObjectIndex, []byte) canopen.SDOAbortCode
+
+	// ObjectDictionary answers uploads and downloads, if the Upload or Download callback is not set
+	ObjectDictionary *od.ObjectDictionary
 }
 
 func (server *Server) Listen(bus *can.Bus) error {
@@ -25,8 +40,23 @@ func (server *Server) Listen(bus *can.Bus) error {
 	server.bus = bus
 	server.client = &canopen.Client{Bus: server.bus, Timeout: time.Second * 2}
 	server.messageQueue = make(chan canopen.Frame, 500)
-	server.clientRequestId = canopen.MessageTypeRSDO + uint16(server.NodeId)
-	server.serverResponseId = canopen.MessageTypeTSDO + uint16(server.NodeId)
+
+	// A node id set with SetNodeID before is kept
+	server.lock.Lock()
+	if server.clientRequestId == 0 {
+		server.setNodeID(server.NodeId)
+	}
+	server.lock.Unlock()
+
+	if server.ObjectDictionary != nil {
+		if server.Upload == nil {
+			server.Upload = server.ObjectDictionary.Read
+		}
+
+		if server.Download == nil {
+			server.Download = server.ObjectDictionary.Write
+		}
+	}
 
 	// Setup function to listen to new requests
 	server.setupListener()
@@ -38,16 +68,56 @@ func (server *Server) Listen(bus *can.Bus) error {
 	return server.bus.ConnectAndPublish()
 }
 
+// SetNodeID changes the node id of the server, e.g. after it was assigned by LSS.
+// The SDO COB-IDs are derived from the new node id.
+func (server *Server) SetNodeID(nodeID uint8) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	server.setNodeID(nodeID)
+}
+
+// setNodeID sets the node id and the COB-IDs, the lock must be held
+func (server *Server) setNodeID(nodeID uint8) {
+	server.nodeID = nodeID
+	server.clientRequestId = canopen.MessageTypeRSDO + uint16(nodeID)
+	server.serverResponseId = canopen.MessageTypeTSDO + uint16(nodeID)
+}
+
+// NodeID returns the current node id of the server.
+func (server *Server) NodeID() uint8 {
+	server.lock.RLock()
+	defer server.lock.RUnlock()
+
+	return server.nodeID
+}
+
+// cobIDs returns the COB-IDs of the client requests and the server responses
+func (server *Server) cobIDs() (uint16, uint16) {
+	server.lock.RLock()
+	defer server.lock.RUnlock()
+
+	return server.clientRequestId, server.serverResponseId
+}
+
 func (server *Server) setupListener() {
 	// Setup listener
 	server.bus.SubscribeFunc(func(frame can.Frame) {
 		coFrame := canopen.CANopenFrame(frame)
 
 		// Check if the frame is intended for us and is SDO
-		if coFrame.NodeID() == server.NodeId && coFrame.MessageType() == canopen.MessageTypeRSDO && len(coFrame.Data) == 8 {
+		if coFrame.NodeID() == server.NodeID() && coFrame.MessageType() == canopen.MessageTypeRSDO && len(coFrame.Data) == 8 {
+			// The frames of a block transfer are received by the transfer itself
+			if server.blockTransfer.Load() {
+				return
+			}
+
 			// Check that it is a new SDO request
 			ccs, _, _, _ := sdo.ProcessRequestByte(coFrame.Data[0])
-			if ccs == sdo.InitiateUploadRequest || ccs == sdo.InitiateDownloadRequest {
+			// cs is stored in bit 0 for block downloads and in bits 0-1 for block uploads
+			isBlockRequest := ccs == sdo.ClientBlockDownload && coFrame.Data[0]&0x1 == sdo.BlockInitiate ||
+				ccs == sdo.ClientBlockUpload && coFrame.Data[0]&0x3 == sdo.BlockInitiate
+			if ccs == sdo.InitiateUploadRequest || ccs == sdo.InitiateDownloadRequest || isBlockRequest {
 				server.messageQueue <- coFrame
 			}
 		}
@@ -64,32 +134,63 @@ func (server *Server) processMessageQueue() {
 
 		} else if ccs == sdo.InitiateDownloadRequest {
 			server.handleDownload(coFrame)
+
+		} else if ccs == sdo.ClientBlockUpload {
+			server.handleBlockUpload(coFrame)
+
+		} else if ccs == sdo.ClientBlockDownload {
+			server.handleBlockDownload(coFrame)
 		}
 	}
 }
 
 func (server *Server) publishError(errorCode canopen.SDOAbortCode, objectIndex canopen.ObjectIndex) error {
-	errorData := binary.LittleEndian.AppendUint32([]byte{}, uint32(errorCode))
-	return server.publish(append(append([]byte{byte(sdo.AbortTransfer << 5)}, objectIndex.Bytes()...), errorData...))
+	return server.publish(sdo.AbortData(errorCode, objectIndex))
 }
 
 func (server *Server) publish(payload []byte) error {
+	return server.publishMinDuration(payload, 10*time.Millisecond)
+}
+
+func (server *Server) publishMinDuration(payload []byte, min time.Duration) error {
 	// Pad the result to always have 8 bytes
 	payload = sdo.Pad(payload, 8)
 
-	return server.bus.Publish(can.Frame{
-		ID:     uint32(server.serverResponseId),
+	_, serverResponseId := server.cobIDs()
+	return server.bus.PublishMinDuration(can.Frame{
+		ID:     uint32(serverResponseId),
 		Length: 8,
 		Data: [8]byte{
 			payload[0], payload[1], payload[2], payload[3], payload[4], payload[5], payload[6], payload[7],
 		},
-	})
+	}, min)
 }
 
 func (server *Server) publishAndWait(payload []byte) (*canopen.Response, error) {
 	// Pad the result to always have 8 bytes
 	payload = sdo.Pad(payload, 8)
 
-	req := canopen.NewRequest(canopen.NewFrame(server.serverResponseId, payload), uint32(server.clientRequestId))
+	clientRequestId, serverResponseId := server.cobIDs()
+	req := canopen.NewRequest(canopen.NewFrame(serverResponseId, payload), uint32(clientRequestId))
 	return server.client.Do(req)
 }
+
+// beginBlockTransfer subscribes to the client requests of a block transfer.
+// Until the transfer ends, no new requests are accepted.
+func (server *Server) beginBlockTransfer() *canopen.Subscription {
+	clientRequestId, _ := server.cobIDs()
+	sub := canopen.Subscribe(server.bus, clientRequestId, sdo.MaxBlockSize+1)
+	server.blockTransfer.Store(true)
+	return sub
+}
+
+func (server *Server) endBlockTransfer(sub *canopen.Subscription) {
+	server.blockTransfer.Store(false)
+	sub.Close()
+}
+
+// isAbort checks if the client aborted the transfer.
+// The whole byte is compared, because segments of a block use all bits for c and seqno.
+func isAbort(frame canopen.Frame) bool {
+	return frame.Data[0] == byte(sdo.AbortTransfer<<5)
+}
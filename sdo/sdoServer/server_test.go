@@ -0,0 +1,184 @@
+package sdoServer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"github.com/FabianPetersen/canopen/sdo"
+	"github.com/FabianPetersen/canopen/sdo/sdoClient"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listen starts a server for node 1 which stores the downloaded values and returns the bus of a client
+func listen(t *testing.T, values map[canopen.ObjectIndex][]byte) *can.Bus {
+	a, b := net.Pipe()
+	clientBus := can.NewBus(can.NewReadWriteCloser(a), "client")
+	serverBus := can.NewBus(can.NewReadWriteCloser(b), "server")
+	go clientBus.ConnectAndPublish()
+	t.Cleanup(func() {
+		clientBus.Disconnect()
+		serverBus.Disconnect()
+	})
+
+	var lock sync.Mutex
+	server := &Server{
+		NodeId: 1,
+		Upload: func(objectIndex canopen.ObjectIndex) ([]byte, canopen.SDOAbortCode) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if value, ok := values[objectIndex]; ok {
+				return value, canopen.NO_ERROR
+			}
+			return nil, canopen.SDO_ERR_NO_OBJECT
+		},
+		Download: func(objectIndex canopen.ObjectIndex, data []byte) canopen.SDOAbortCode {
+			lock.Lock()
+			defer lock.Unlock()
+
+			values[objectIndex] = data
+			return canopen.NO_ERROR
+		},
+	}
+	go server.Listen(serverBus)
+	time.Sleep(10 * time.Millisecond)
+
+	return clientBus
+}
+
+// blockData returns data which doesn't fill the last segment of 7 bytes and needs more than one block of 127 segments
+func blockData() []byte {
+	data := make([]byte, 1000)
+	for i := range data {
+		data[i] = byte(i * 3)
+	}
+
+	return data
+}
+
+func TestBlockDownload(t *testing.T) {
+	values := map[canopen.ObjectIndex][]byte{}
+	bus := listen(t, values)
+
+	data := blockData()
+	objectIndex := canopen.NewObjectIndex(0x2000, 1)
+	err := sdoClient.Download{
+		ObjectIndex:   objectIndex,
+		Data:          data,
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		Timeout:       time.Second,
+	}.DoBlock(bus)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(values[objectIndex], data) {
+		t.Log("Data does not match", len(values[objectIndex]))
+		t.FailNow()
+	}
+}
+
+func TestBlockUpload(t *testing.T) {
+	data := blockData()
+	objectIndex := canopen.NewObjectIndex(0x2000, 1)
+	bus := listen(t, map[canopen.ObjectIndex][]byte{objectIndex: data})
+
+	// The default block size and a block size which results in many blocks
+	for _, blockSize := range []uint8{0, 10} {
+		received, err := sdoClient.Upload{
+			ObjectIndex:   objectIndex,
+			RequestCobID:  canopen.MessageTypeRSDO + 1,
+			ResponseCobID: canopen.MessageTypeTSDO + 1,
+			BlockSize:     blockSize,
+			Timeout:       time.Second,
+		}.DoBlock(bus)
+		if err != nil {
+			t.Log(blockSize, err)
+			t.FailNow()
+		}
+
+		if !bytes.Equal(received, data) {
+			t.Log("Data does not match", blockSize, len(received))
+			t.FailNow()
+		}
+	}
+
+	// Unknown objects are aborted by the server
+	if _, err := (sdoClient.Upload{
+		ObjectIndex:   canopen.NewObjectIndex(0x2001, 0),
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		Timeout:       time.Second,
+	}).DoBlock(bus); err == nil {
+		t.Log("Expected abort")
+		t.FailNow()
+	}
+}
+
+func TestBlockCRC(t *testing.T) {
+	data := blockData()
+	objectIndex := canopen.NewObjectIndex(0x2000, 1)
+	values := map[canopen.ObjectIndex][]byte{}
+	bus := listen(t, values)
+
+	responses := canopen.Subscribe(bus, canopen.MessageTypeTSDO+1, 300)
+	defer responses.Close()
+
+	err := sdoClient.Download{
+		ObjectIndex:   objectIndex,
+		Data:          data,
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		Timeout:       time.Second,
+	}.DoBlock(bus)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// scs = 5, sc = 1 (server supports CRC), ss = 0
+	if frame, _ := responses.Receive(time.Second); frame.Data[0] != 0xA4 {
+		t.Log("Unexpected initiate response", frame.Data)
+		t.FailNow()
+	}
+
+	received, err := sdoClient.Upload{
+		ObjectIndex:   objectIndex,
+		RequestCobID:  canopen.MessageTypeRSDO + 1,
+		ResponseCobID: canopen.MessageTypeTSDO + 1,
+		Timeout:       time.Second,
+	}.DoBlock(bus)
+	if err != nil || !bytes.Equal(received, data) {
+		t.Log("Unexpected data", len(received), err)
+		t.FailNow()
+	}
+
+	// Skip the responses of the download until the initiate response, scs = 6, sc = 1, s = 1, ss = 0
+	next := func(header byte, mask byte) canopen.Frame {
+		for {
+			frame, err := responses.Receive(time.Second)
+			if err != nil {
+				t.Log(err)
+				t.FailNow()
+			}
+
+			if frame.Data[0]&mask == header {
+				return frame
+			}
+		}
+	}
+	next(0xC6, 0xFF)
+
+	// scs = 6, n, ss = 1 (end) with the CRC of the data
+	frame := next(0xC1, 0xE3)
+	if crc := binary.LittleEndian.Uint16(frame.Data[1:3]); crc != sdo.CRC16(data) {
+		t.Log("Unexpected CRC", crc)
+		t.FailNow()
+	}
+}
@@ -10,20 +10,25 @@ func (server *Server) handleUpload(frame canopen.Frame) {
 	// Read all the data
 	objectIndex := frame.ObjectIndex()
 	data, uploadErr := server.Upload(objectIndex)
+
+	if uploadErr == canopen.NO_ERROR {
+		server.upload(data, objectIndex)
+	} else {
+		server.publishError(uploadErr, objectIndex)
+	}
+}
+
+func (server *Server) upload(data []byte, objectIndex canopen.ObjectIndex) {
 	size := len(data)
 
 	// Set scs = 2, s=1 (Always indicate size)
 	headerByte := (byte(sdo.InitiateUploadResponse) << 5) + 1
 
 	// Can be sent as expedited
-	if uploadErr == canopen.NO_ERROR {
-		if size <= 4 {
-			server.expeditedUpload(headerByte, size, data, objectIndex)
-		} else {
-			server.ordinaryUpload(headerByte, size, data, objectIndex)
-		}
+	if size <= 4 {
+		server.expeditedUpload(headerByte, size, data, objectIndex)
 	} else {
-		server.publishError(uploadErr, objectIndex)
+		server.ordinaryUpload(headerByte, size, data, objectIndex)
 	}
 }
 
@@ -86,3 +91,136 @@ func (server *Server) ordinaryUpload(headerByte byte, size int, data []byte, obj
 	// Send the last segment
 	server.publish(currentFrameData)
 }
+
+func (server *Server) handleBlockUpload(frame canopen.Frame) {
+	objectIndex := frame.ObjectIndex()
+
+	// cc = 1 (client supports CRC)
+	hasCRC := sdo.HasBit(frame.Data[0], 2)
+
+	// blksize (segments per block), pst (protocol switch threshold)
+	blockSize := frame.Data[4]
+	pst := int(frame.Data[5])
+	if blockSize == 0 || blockSize > sdo.MaxBlockSize {
+		server.publishError(canopen.SDO_ERR_BLOCK_SIZE, objectIndex)
+		return
+	}
+
+	// Read all the data
+	data, uploadErr := server.Upload(objectIndex)
+	if uploadErr != canopen.NO_ERROR {
+		server.publishError(uploadErr, objectIndex)
+		return
+	}
+
+	// Switch to an expedited or segmented upload, if the client requests it for small objects
+	if pst > 0 && len(data) <= pst {
+		server.upload(data, objectIndex)
+		return
+	}
+
+	sub := server.beginBlockTransfer()
+	defer server.endBlockTransfer(sub)
+
+	// Accept the request, scs = 6, sc (server supports CRC), s = 1 (Always indicate size), ss = 0 (initiate)
+	headerByte := byte(sdo.ServerBlockUpload<<5) | 1<<1 | sdo.BlockInitiate
+	if hasCRC {
+		headerByte = sdo.SetBit(headerByte, 2)
+	}
+	sizeData := binary.LittleEndian.AppendUint32([]byte{}, uint32(len(data)))
+	initData := append(append([]byte{headerByte}, objectIndex.Bytes()...), sizeData...)
+	if err := server.publish(initData); err != nil {
+		return
+	}
+
+	// Wait for the client to start the upload, ccs = 5, cs = 3 (start)
+	if _, ok := server.receiveBlockUploadRequest(sub, sdo.BlockStartUpload, objectIndex); !ok {
+		return
+	}
+
+	segments := sdo.SplitN(data, 7)
+	for index := 0; index < len(segments); {
+		// Send the next block
+		count := len(segments) - index
+		if count > int(blockSize) {
+			count = int(blockSize)
+		}
+
+		for seq := 1; seq <= count; seq++ {
+			headerByte := byte(seq)
+
+			// Set c (1 == no more segments)
+			if index+seq == len(segments) {
+				headerByte = sdo.SetBit(headerByte, 7)
+			}
+
+			// Pad the data to always have 7 bytes
+			segmentData := sdo.Pad(segments[index+seq-1], 7)
+			if err := server.publishMinDuration(append([]byte{headerByte}, segmentData...), segmentDelay); err != nil {
+				return
+			}
+		}
+
+		// Wait for the client to confirm the block, ccs = 5, cs = 2 (block ack)
+		resp, ok := server.receiveBlockUploadRequest(sub, sdo.BlockAck, objectIndex)
+		if !ok {
+			return
+		}
+
+		ackSeq := int(resp.Data[1])
+		if ackSeq > count {
+			server.publishError(canopen.SDO_ERR_BLOCK_SEQUENCE, objectIndex)
+			return
+		}
+
+		blockSize = resp.Data[2]
+		if blockSize == 0 || blockSize > sdo.MaxBlockSize {
+			server.publishError(canopen.SDO_ERR_BLOCK_SIZE, objectIndex)
+			return
+		}
+
+		// Segments after ackseq are sent again in the next block
+		index += ackSeq
+	}
+
+	// The end confirmation of the client is not mistaken for a new request,
+	// accept new requests before the client is able to send them
+	server.blockTransfer.Store(false)
+
+	// End the transfer, scs = 6, n (number of empty bytes in the last segment), ss = 1 (end), crc
+	n := byte(7 - len(segments[len(segments)-1]))
+	endData := []byte{byte(sdo.ServerBlockUpload<<5) | n<<2 | sdo.BlockEnd}
+	if hasCRC {
+		endData = binary.LittleEndian.AppendUint16(endData, sdo.CRC16(data))
+	}
+	if err := server.publish(endData); err != nil {
+		return
+	}
+
+	// Wait for the client to confirm the end, ccs = 5, cs = 1 (end)
+	server.receiveBlockUploadRequest(sub, sdo.BlockEnd, objectIndex)
+}
+
+// receiveBlockUploadRequest waits for the next request of a block upload and checks the client sub command.
+// The transfer is aborted if the request does not arrive on time or is not expected.
+func (server *Server) receiveBlockUploadRequest(sub *canopen.Subscription, cs byte, objectIndex canopen.ObjectIndex) (canopen.Frame, bool) {
+	resp, err := sub.Receive(server.client.Timeout)
+	if err != nil {
+		// Abort request (client timeout)
+		server.publishError(canopen.SDO_ERR_TIMEOUT, objectIndex)
+		return resp, false
+	}
+
+	if isAbort(resp) {
+		return resp, false
+	}
+
+	ccs := sdo.ClientCommandSpecifier(resp.Data[0] >> 5)
+	if ccs != sdo.ClientBlockUpload || resp.Data[0]&0x3 != cs {
+		// Abort request (wrong ccs)
+		server.publishError(canopen.SDO_ERR_COMMAND, objectIndex)
+		return resp, false
+	}
+
+	return resp, true
+}
@@ -1,6 +1,9 @@
 package sdo
 
-import "github.com/FabianPetersen/canopen"
+import (
+	"encoding/binary"
+	"github.com/FabianPetersen/canopen"
+)
 
 func HasBit(n uint8, pos uint) bool {
 	val := n & (1 << pos)
@@ -19,6 +22,12 @@ func GetAbortCodeBytes(frame canopen.Frame) []uint8 {
 	return []uint8{}
 }
 
+// AbortData returns the 8 data bytes of an abort transfer frame.
+func AbortData(errorCode canopen.SDOAbortCode, objectIndex canopen.ObjectIndex) []uint8 {
+	errorData := binary.LittleEndian.AppendUint32([]byte{}, uint32(errorCode))
+	return append(append([]byte{byte(AbortTransfer << 5)}, objectIndex.Bytes()...), errorData...)
+}
+
 // SplitN splits b into a list of n sized bytes
 func SplitN(b []byte, n int) [][]byte {
 	if len(b) < n {
@@ -60,6 +69,7 @@ const (
 
 	InitiateUploadRequest ClientCommandSpecifier = 2
 	UploadSegmentRequest  ClientCommandSpecifier = 3
+	ClientBlockUpload     ClientCommandSpecifier = 5
 )
 
 type ServerCommandSpecifier byte
@@ -71,10 +81,22 @@ const (
 
 	InitiateUploadResponse ServerCommandSpecifier = 2
 	UploadSegmentResponse  ServerCommandSpecifier = 0
+	ServerBlockUpload      ServerCommandSpecifier = 6
 
 	AbortTransfer ServerCommandSpecifier = 4
 )
 
+// Sub commands (cs/ss) of block transfers, stored in the lowest bits of the first byte
+const (
+	BlockInitiate    byte = 0
+	BlockEnd         byte = 1
+	BlockAck         byte = 2
+	BlockStartUpload byte = 3
+)
+
+// MaxBlockSize is the highest number of segments per block
+const MaxBlockSize = 127
+
 func ProcessRequestByte(clientData byte) (ClientCommandSpecifier, bool, bool, byte) {
 	clientCommandSpecifier := clientData >> 5
 	isExpedited := HasBit(clientData, 1)
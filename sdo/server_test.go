@@ -1,4 +1,8 @@
-package sdo
+//go:build hardware
+
+// The tests need the CAN interface can0. They are in an external test package,
+// because sdoClient and sdoServer import this package.
+package sdo_test
 
 import (
 	"bytes"
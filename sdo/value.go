@@ -0,0 +1,265 @@
+package sdo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/FabianPetersen/canopen"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	// ErrLength is returned if the data doesn't have the size of the data type
+	ErrLength = errors.New("data length does not match the data type")
+	// ErrDataType is returned if a Go type can't represent the data type
+	ErrDataType = errors.New("data type does not match the value type")
+	// ErrRange is returned if a value doesn't fit into the data type
+	ErrRange = errors.New("value out of range of the data type")
+)
+
+// Value is a Go type which represents values of CANopen data types:
+//   - bool: BOOLEAN
+//   - unsigned integers: UNSIGNED_8 to UNSIGNED_64, the data type must fit into the type
+//   - signed integers: INTEGER_8 to INTEGER_64, the data type must fit into the type
+//   - float32: REAL_32, float64: REAL_32 and REAL_64
+//   - string: VISIBLE_STRING and UNICODE_STRING
+//   - []byte: any data type, the data is not converted
+//   - time.Time: TIME_OF_DAY, time.Duration: TIME_DIFFERENCE
+type Value interface {
+	bool | uint8 | uint16 | uint32 | uint64 | int8 | int16 | int32 | int64 | float32 | float64 | string | []byte | time.Time | time.Duration
+}
+
+// Decode returns the value of data with the data type.
+// The length of data must match the size of the data type.
+func Decode[T Value](datatype SDODataType, data []byte) (T, error) {
+	var value T
+	if size := DataTypeSize(datatype); size > 0 && len(data) != size {
+		return value, fmt.Errorf("%d bytes of data type %X (expected %d): %w", len(data), datatype, size, ErrLength)
+	}
+
+	var err error
+	switch v := any(&value).(type) {
+	case *bool:
+		if err = expectDataType(datatype, *v, DATA_TYPE_BOOLEAN); err == nil {
+			*v = data[0] != 0
+		}
+	case *uint8:
+		*v, err = decodeUnsigned[uint8](datatype, data)
+	case *uint16:
+		*v, err = decodeUnsigned[uint16](datatype, data)
+	case *uint32:
+		*v, err = decodeUnsigned[uint32](datatype, data)
+	case *uint64:
+		*v, err = decodeUnsigned[uint64](datatype, data)
+	case *int8:
+		*v, err = decodeSigned[int8](datatype, data)
+	case *int16:
+		*v, err = decodeSigned[int16](datatype, data)
+	case *int32:
+		*v, err = decodeSigned[int32](datatype, data)
+	case *int64:
+		*v, err = decodeSigned[int64](datatype, data)
+	case *float32:
+		if err = expectDataType(datatype, *v, DATA_TYPE_REAL_32); err == nil {
+			*v = math.Float32frombits(binary.LittleEndian.Uint32(data))
+		}
+	case *float64:
+		switch datatype {
+		case DATA_TYPE_REAL_32:
+			*v = float64(math.Float32frombits(binary.LittleEndian.Uint32(data)))
+		case DATA_TYPE_REAL_64:
+			*v = math.Float64frombits(binary.LittleEndian.Uint64(data))
+		default:
+			err = expectDataType(datatype, *v)
+		}
+	case *string:
+		switch datatype {
+		case DATA_TYPE_VISIBLE_STRING:
+			// Strings may be padded with null characters
+			*v = strings.TrimRight(string(data), "\x00")
+		case DATA_TYPE_UNICODE_STRING:
+			*v, err = decodeUnicode(data)
+		default:
+			err = expectDataType(datatype, *v)
+		}
+	case *[]byte:
+		*v = append([]byte{}, data...)
+	case *time.Time:
+		if err = expectDataType(datatype, *v, DATA_TYPE_TIME_OF_DAY); err == nil {
+			*v, err = canopen.DecodeTimeOfDay(data)
+		}
+	case *time.Duration:
+		if err = expectDataType(datatype, *v, DATA_TYPE_TIME_DIFFERENCE); err == nil {
+			*v, err = canopen.DecodeTimeDifference(data)
+		}
+	}
+
+	return value, err
+}
+
+// Encode returns the data of a value with the data type.
+// An error is returned if the value doesn't fit into the data type.
+func Encode[T Value](datatype SDODataType, value T) ([]byte, error) {
+	switch v := any(value).(type) {
+	case bool:
+		if err := expectDataType(datatype, v, DATA_TYPE_BOOLEAN); err != nil {
+			return nil, err
+		}
+
+		if v {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case uint8:
+		return encodeUnsigned(datatype, uint64(v))
+	case uint16:
+		return encodeUnsigned(datatype, uint64(v))
+	case uint32:
+		return encodeUnsigned(datatype, uint64(v))
+	case uint64:
+		return encodeUnsigned(datatype, v)
+	case int8:
+		return encodeSigned(datatype, int64(v))
+	case int16:
+		return encodeSigned(datatype, int64(v))
+	case int32:
+		return encodeSigned(datatype, int64(v))
+	case int64:
+		return encodeSigned(datatype, v)
+	case float32:
+		if err := expectDataType(datatype, v, DATA_TYPE_REAL_32); err != nil {
+			return nil, err
+		}
+
+		return binary.LittleEndian.AppendUint32(nil, math.Float32bits(v)), nil
+	case float64:
+		switch datatype {
+		case DATA_TYPE_REAL_32:
+			if math.Abs(v) > math.MaxFloat32 && !math.IsInf(v, 0) {
+				return nil, fmt.Errorf("%g as data type %X: %w", v, datatype, ErrRange)
+			}
+			return binary.LittleEndian.AppendUint32(nil, math.Float32bits(float32(v))), nil
+		case DATA_TYPE_REAL_64:
+			return binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)), nil
+		}
+		return nil, expectDataType(datatype, v)
+	case string:
+		switch datatype {
+		case DATA_TYPE_VISIBLE_STRING:
+			return []byte(v), nil
+		case DATA_TYPE_UNICODE_STRING:
+			var data []byte
+			for _, unit := range utf16.Encode([]rune(v)) {
+				data = binary.LittleEndian.AppendUint16(data, unit)
+			}
+			return data, nil
+		}
+		return nil, expectDataType(datatype, v)
+	case []byte:
+		if size := DataTypeSize(datatype); size > 0 && len(v) != size {
+			return nil, fmt.Errorf("%d bytes of data type %X (expected %d): %w", len(v), datatype, size, ErrLength)
+		}
+		return append([]byte{}, v...), nil
+	case time.Time:
+		if err := expectDataType(datatype, v, DATA_TYPE_TIME_OF_DAY); err != nil {
+			return nil, err
+		}
+		return canopen.EncodeTimeOfDay(v)
+	case time.Duration:
+		if err := expectDataType(datatype, v, DATA_TYPE_TIME_DIFFERENCE); err != nil {
+			return nil, err
+		}
+		return canopen.EncodeTimeDifference(v)
+	}
+
+	return nil, fmt.Errorf("%T as data type %X: %w", value, datatype, ErrDataType)
+}
+
+// IsUnsigned returns true for the UNSIGNED data types.
+func IsUnsigned(datatype SDODataType) bool {
+	switch datatype {
+	case DATA_TYPE_UNSIGNED_8, DATA_TYPE_UNSIGNED_16, DATA_TYPE_UNSIGNED_24, DATA_TYPE_UNSIGNED_32,
+		DATA_TYPE_UNSIGNED_40, DATA_TYPE_UNSIGNED_48, DATA_TYPE_UNSIGNED_56, DATA_TYPE_UNSIGNED_64:
+		return true
+	}
+
+	return false
+}
+
+// expectDataType returns an error if the data type isn't one of the expected data types of the value
+func expectDataType(datatype SDODataType, value any, expected ...SDODataType) error {
+	for _, e := range expected {
+		if datatype == e {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%T as data type %X: %w", value, datatype, ErrDataType)
+}
+
+func decodeUnsigned[T uint8 | uint16 | uint32 | uint64](datatype SDODataType, data []byte) (T, error) {
+	var value T
+	if !IsUnsigned(datatype) || DataTypeSize(datatype) > binary.Size(value) {
+		return value, expectDataType(datatype, value)
+	}
+
+	return T(ParseUInt(append([]byte{}, data...))), nil
+}
+
+func decodeSigned[T int8 | int16 | int32 | int64](datatype SDODataType, data []byte) (T, error) {
+	var value T
+	if !IsReversed(datatype) || DataTypeSize(datatype) > binary.Size(value) {
+		return value, expectDataType(datatype, value)
+	}
+
+	// ParseInt reverses the bytes in place
+	n, err := ParseInt(append([]byte{}, data...))
+	return T(n), err
+}
+
+func encodeUnsigned(datatype SDODataType, value uint64) ([]byte, error) {
+	if !IsUnsigned(datatype) {
+		return nil, expectDataType(datatype, value)
+	}
+
+	size := DataTypeSize(datatype)
+	if size < 8 && value >= 1<<(8*size) {
+		return nil, fmt.Errorf("%d as data type %X: %w", value, datatype, ErrRange)
+	}
+
+	return binary.LittleEndian.AppendUint64(nil, value)[:size], nil
+}
+
+func encodeSigned(datatype SDODataType, value int64) ([]byte, error) {
+	if !IsReversed(datatype) {
+		return nil, expectDataType(datatype, value)
+	}
+
+	size := DataTypeSize(datatype)
+	if bits := 8 * size; size < 8 && (value < -1<<(bits-1) || value >= 1<<(bits-1)) {
+		return nil, fmt.Errorf("%d as data type %X: %w", value, datatype, ErrRange)
+	}
+
+	return binary.LittleEndian.AppendUint64(nil, uint64(value))[:size], nil
+}
+
+func decodeUnicode(data []byte) (string, error) {
+	if len(data)%2 != 0 {
+		return "", fmt.Errorf("%d bytes of data type %X: %w", len(data), DATA_TYPE_UNICODE_STRING, ErrLength)
+	}
+
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i < len(data); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(data[i:]))
+	}
+
+	// Strings may be padded with null characters
+	for len(units) > 0 && units[len(units)-1] == 0 {
+		units = units[:len(units)-1]
+	}
+
+	return string(utf16.Decode(units)), nil
+}
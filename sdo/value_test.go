@@ -0,0 +1,139 @@
+package sdo
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecode(t *testing.T) {
+	data := []byte{0xFE, 0xFF, 0xFF}
+	if value, err := Decode[int32](DATA_TYPE_INTEGER_24, data); err != nil || value != -2 {
+		t.Log("Unexpected INTEGER_24", value, err)
+		t.FailNow()
+	}
+
+	if !bytes.Equal(data, []byte{0xFE, 0xFF, 0xFF}) {
+		t.Log("Data was modified", data)
+		t.FailNow()
+	}
+
+	if value, err := Decode[uint32](DATA_TYPE_UNSIGNED_24, []byte{0x01, 0x02, 0x03}); err != nil || value != 0x030201 {
+		t.Log("Unexpected UNSIGNED_24", value, err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[int64](DATA_TYPE_INTEGER_40, []byte{0x00, 0x00, 0x00, 0x00, 0x80}); err != nil || value != -1<<39 {
+		t.Log("Unexpected INTEGER_40", value, err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[float64](DATA_TYPE_REAL_32, []byte{0x00, 0x00, 0xC0, 0x3F}); err != nil || value != 1.5 {
+		t.Log("Unexpected REAL_32", value, err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[string](DATA_TYPE_VISIBLE_STRING, []byte("abc\x00\x00")); err != nil || value != "abc" {
+		t.Log("Unexpected VISIBLE_STRING", value, err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[string](DATA_TYPE_UNICODE_STRING, []byte{0xE4, 0x00, 0x3D, 0xD8, 0x00, 0xDE}); err != nil || value != "ä😀" {
+		t.Log("Unexpected UNICODE_STRING", value, err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[time.Duration](DATA_TYPE_TIME_DIFFERENCE, []byte{0x10, 0x27, 0x00, 0x00, 0x01, 0x00}); err != nil || value != 24*time.Hour+10*time.Second {
+		t.Log("Unexpected TIME_DIFFERENCE", value, err)
+		t.FailNow()
+	}
+
+	for _, err := range []error{
+		errorOf(Decode[uint16](DATA_TYPE_UNSIGNED_16, []byte{0x01})),
+		errorOf(Decode[bool](DATA_TYPE_BOOLEAN, []byte{})),
+		errorOf(Decode[float64](DATA_TYPE_REAL_64, []byte{0x00, 0x00, 0xC0, 0x3F})),
+		errorOf(Decode[string](DATA_TYPE_UNICODE_STRING, []byte{0x41})),
+	} {
+		if !errors.Is(err, ErrLength) {
+			t.Log("Expected length error", err)
+			t.FailNow()
+		}
+	}
+
+	for _, err := range []error{
+		errorOf(Decode[uint16](DATA_TYPE_UNSIGNED_32, []byte{0x01, 0x02, 0x03, 0x04})),
+		errorOf(Decode[uint32](DATA_TYPE_INTEGER_32, []byte{0x01, 0x02, 0x03, 0x04})),
+		errorOf(Decode[float32](DATA_TYPE_REAL_64, make([]byte, 8))),
+		errorOf(Decode[string](DATA_TYPE_OCTET_STRING, []byte{0x41})),
+	} {
+		if !errors.Is(err, ErrDataType) {
+			t.Log("Expected data type error", err)
+			t.FailNow()
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	for _, test := range []struct {
+		data     []byte
+		expected []byte
+	}{
+		{data: errorData(Encode(DATA_TYPE_BOOLEAN, true)), expected: []byte{0x01}},
+		{data: errorData(Encode(DATA_TYPE_UNSIGNED_24, uint32(0x030201))), expected: []byte{0x01, 0x02, 0x03}},
+		{data: errorData(Encode(DATA_TYPE_INTEGER_24, int32(-2))), expected: []byte{0xFE, 0xFF, 0xFF}},
+		{data: errorData(Encode(DATA_TYPE_UNSIGNED_56, uint64(1)<<55)), expected: []byte{0, 0, 0, 0, 0, 0, 0x80}},
+		{data: errorData(Encode(DATA_TYPE_REAL_32, float32(1.5))), expected: []byte{0x00, 0x00, 0xC0, 0x3F}},
+		{data: errorData(Encode(DATA_TYPE_REAL_32, 1.5)), expected: []byte{0x00, 0x00, 0xC0, 0x3F}},
+		{data: errorData(Encode(DATA_TYPE_UNICODE_STRING, "ä😀")), expected: []byte{0xE4, 0x00, 0x3D, 0xD8, 0x00, 0xDE}},
+		{data: errorData(Encode(DATA_TYPE_TIME_DIFFERENCE, 24*time.Hour+10*time.Second)), expected: []byte{0x10, 0x27, 0x00, 0x00, 0x01, 0x00}},
+	} {
+		if !bytes.Equal(test.data, test.expected) {
+			t.Log("Unexpected data", test.data, "expected", test.expected)
+			t.FailNow()
+		}
+	}
+
+	for _, test := range []struct {
+		err      error
+		expected error
+	}{
+		{err: errorOf(Encode(DATA_TYPE_UNSIGNED_24, uint32(1<<24))), expected: ErrRange},
+		{err: errorOf(Encode(DATA_TYPE_INTEGER_24, int32(-1<<23-1))), expected: ErrRange},
+		{err: errorOf(Encode(DATA_TYPE_INTEGER_8, int16(128))), expected: ErrRange},
+		{err: errorOf(Encode(DATA_TYPE_REAL_32, math.MaxFloat64)), expected: ErrRange},
+		{err: errorOf(Encode(DATA_TYPE_INTEGER_16, uint16(1))), expected: ErrDataType},
+		{err: errorOf(Encode(DATA_TYPE_REAL_64, float32(1))), expected: ErrDataType},
+		{err: errorOf(Encode(DATA_TYPE_UNSIGNED_32, []byte{0x01})), expected: ErrLength},
+	} {
+		if !errors.Is(test.err, test.expected) {
+			t.Log("Unexpected error", test.err, "expected", test.expected)
+			t.FailNow()
+		}
+	}
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 10*int(time.Millisecond), time.UTC)
+	data, err := Encode(DATA_TYPE_TIME_OF_DAY, now)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if value, err := Decode[time.Time](DATA_TYPE_TIME_OF_DAY, data); err != nil || !value.Equal(now) {
+		t.Log("Unexpected TIME_OF_DAY", value, err)
+		t.FailNow()
+	}
+}
+
+func errorOf[T any](_ T, err error) error {
+	return err
+}
+
+func errorData(data []byte, err error) []byte {
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
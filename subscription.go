@@ -0,0 +1,73 @@
+package canopen
+
+import (
+	"context"
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"time"
+)
+
+// A Subscription receives all data frames with a specific COB-ID from a bus.
+// Frames are dropped if the buffer is full, so a slow reader never blocks the bus.
+type Subscription struct {
+	// C receives the subscribed frames
+	C <-chan Frame
+
+	cobID   uint16
+	bus     *can.Bus
+	handler can.Handler
+}
+
+// Subscribe returns a subscription for frames with the COB-ID cobID buffering up to size frames.
+func Subscribe(bus *can.Bus, cobID uint16, size int) *Subscription {
+	frames := make(chan Frame, size)
+	sub := &Subscription{
+		C:     frames,
+		cobID: cobID,
+		bus:   bus,
+	}
+
+	sub.handler = can.NewHandler(func(frm can.Frame) {
+		// Only standard data frames are of interest
+		if frm.ID&(MaskEff|MaskRtr|MaskErr) != 0 || uint16(frm.ID&MaskIDSff) != cobID {
+			return
+		}
+
+		select {
+		case frames <- CANopenFrame(frm):
+		default:
+		}
+	})
+	bus.Subscribe(sub.handler)
+
+	return sub
+}
+
+// Receive waits for the next frame.
+// If the frame doesn't arrive on time, an error is returned.
+func (sub *Subscription) Receive(timeout time.Duration) (Frame, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return sub.ReceiveContext(ctx)
+}
+
+// ReceiveContext waits for the next frame until ctx is done.
+// The returned error wraps context.DeadlineExceeded or context.Canceled.
+func (sub *Subscription) ReceiveContext(ctx context.Context) (Frame, error) {
+	select {
+	case frm := <-sub.C:
+		return frm, nil
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return Frame{}, fmt.Errorf("timeout error waiting for %X: %w", sub.cobID, ctx.Err())
+		}
+
+		return Frame{}, fmt.Errorf("waiting for %X: %w", sub.cobID, ctx.Err())
+	}
+}
+
+// Close removes the subscription from the bus.
+func (sub *Subscription) Close() {
+	sub.bus.Unsubscribe(sub.handler)
+}
@@ -0,0 +1,234 @@
+package syncobj
+
+import (
+	"errors"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// ErrOutsideWindow is returned if synchronous processing is requested after the synchronous window expired
+var ErrOutsideWindow = errors.New("outside of the synchronous window")
+
+// EventType describes a SYNC event
+type EventType uint8
+
+const (
+	// EventSync is emitted for every received SYNC message
+	EventSync EventType = iota
+	// EventMissing is emitted if no SYNC message was received within 1.5 communication cycle periods
+	// and for every further period without a SYNC message
+	EventMissing
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventSync:
+		return "SYNC"
+	case EventMissing:
+		return "SYNC missing"
+	}
+
+	return "Unknown"
+}
+
+// Event is emitted by the consumer for every received or missing SYNC message.
+type Event struct {
+	Type EventType
+	// Counter is the synchronous counter of the SYNC message, if HasCounter is set
+	Counter    uint8
+	HasCounter bool
+	// Time is the receive time of the SYNC message or the time the missing SYNC was detected
+	Time time.Time
+}
+
+// Consumer receives SYNC messages and delivers them to its subscribers.
+type Consumer struct {
+	bus     *can.Bus
+	handler can.Handler
+	cobID   uint16
+
+	lock        sync.Mutex
+	period      time.Duration
+	window      time.Duration
+	last        Event
+	missed      uint64
+	timer       *time.Timer
+	subscribers map[int]func(Event)
+	nextID      int
+}
+
+// NewConsumer returns a consumer for the SYNC messages with the COB-ID (0x1005), 0 uses the default 0x080.
+func NewConsumer(bus *can.Bus, cobID uint16) *Consumer {
+	if cobID == 0 {
+		cobID = canopen.MessageTypeSync
+	}
+
+	consumer := &Consumer{
+		bus:         bus,
+		cobID:       cobID,
+		subscribers: map[int]func(Event){},
+	}
+
+	consumer.handler = can.NewHandler(consumer.handle)
+	bus.Subscribe(consumer.handler)
+
+	return consumer
+}
+
+// Close stops listening to the SYNC messages.
+func (consumer *Consumer) Close() {
+	consumer.bus.Unsubscribe(consumer.handler)
+
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if consumer.timer != nil {
+		consumer.timer.Stop()
+		consumer.timer = nil
+	}
+}
+
+// Subscribe adds a function which is called for every event and returns a function to remove it again.
+// The function must not block because it is called while the bus dispatches frames.
+func (consumer *Consumer) Subscribe(fn func(Event)) (unsubscribe func()) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	id := consumer.nextID
+	consumer.nextID++
+	consumer.subscribers[id] = fn
+
+	return func() {
+		consumer.lock.Lock()
+		defer consumer.lock.Unlock()
+
+		delete(consumer.subscribers, id)
+	}
+}
+
+// SetPeriod sets the expected communication cycle period (0x1006), 0 disables the detection of missing SYNC messages.
+func (consumer *Consumer) SetPeriod(period time.Duration) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	consumer.period = period
+	if consumer.timer != nil {
+		consumer.timer.Stop()
+		consumer.timer = nil
+	}
+}
+
+// SetWindow sets the synchronous window length (0x1007), 0 disables the window.
+func (consumer *Consumer) SetWindow(window time.Duration) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	consumer.window = window
+}
+
+// Last returns the last received SYNC message.
+func (consumer *Consumer) Last() (Event, bool) {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	return consumer.last, !consumer.last.Time.IsZero()
+}
+
+// Missed returns the number of missing SYNC messages.
+func (consumer *Consumer) Missed() uint64 {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	return consumer.missed
+}
+
+// InWindow returns true if the last SYNC message was received within the synchronous window.
+// Synchronous PDOs must only be processed inside the window.
+func (consumer *Consumer) InWindow() bool {
+	return consumer.CheckWindow() == nil
+}
+
+// CheckWindow returns ErrOutsideWindow if no SYNC message was received yet or the synchronous window expired.
+func (consumer *Consumer) CheckWindow() error {
+	consumer.lock.Lock()
+	defer consumer.lock.Unlock()
+
+	if consumer.last.Time.IsZero() {
+		return ErrOutsideWindow
+	}
+
+	if consumer.window > 0 && time.Since(consumer.last.Time) > consumer.window {
+		return ErrOutsideWindow
+	}
+
+	return nil
+}
+
+func (consumer *Consumer) handle(frm can.Frame) {
+	if frm.ID&(canopen.MaskEff|canopen.MaskRtr|canopen.MaskErr) != 0 || uint16(frm.ID&canopen.MaskIDSff) != consumer.cobID || frm.Length > 1 {
+		return
+	}
+
+	event := Event{
+		Type:       EventSync,
+		Counter:    frm.Data[0],
+		HasCounter: frm.Length == 1,
+		Time:       time.Now(),
+	}
+	if !event.HasCounter {
+		event.Counter = 0
+	}
+
+	consumer.lock.Lock()
+	consumer.last = event
+
+	// Restart the detection of missing SYNC messages
+	if consumer.period > 0 {
+		timeout := consumer.period * 3 / 2
+		if consumer.timer == nil {
+			consumer.timer = time.AfterFunc(timeout, consumer.timeout)
+		} else {
+			consumer.timer.Reset(timeout)
+		}
+	}
+	subscribers := consumer.subscriberList()
+	consumer.lock.Unlock()
+
+	emit(subscribers, event)
+}
+
+func (consumer *Consumer) timeout() {
+	consumer.lock.Lock()
+
+	// The SYNC message may have arrived while the timer fired
+	if consumer.period == 0 || consumer.timer == nil || time.Since(consumer.last.Time) < consumer.period*3/2 {
+		consumer.lock.Unlock()
+		return
+	}
+
+	consumer.missed++
+	consumer.timer.Reset(consumer.period)
+	event := Event{Type: EventMissing, Time: time.Now()}
+	subscribers := consumer.subscriberList()
+	consumer.lock.Unlock()
+
+	emit(subscribers, event)
+}
+
+// subscriberList returns a copy of the subscribers, the lock must be held
+func (consumer *Consumer) subscriberList() []func(Event) {
+	subscribers := make([]func(Event), 0, len(consumer.subscribers))
+	for _, fn := range consumer.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+
+	return subscribers
+}
+
+func emit(subscribers []func(Event), event Event) {
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
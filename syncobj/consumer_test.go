@@ -0,0 +1,70 @@
+package syncobj
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"testing"
+	"time"
+)
+
+func TestConsumer(t *testing.T) {
+	bus := can.NewBus(nil, "test")
+	consumer := NewConsumer(bus, 0)
+	defer consumer.Close()
+
+	consumer.SetPeriod(20 * time.Millisecond)
+	consumer.SetWindow(10 * time.Millisecond)
+
+	events := make(chan Event, 10)
+	unsubscribe := consumer.Subscribe(func(event Event) {
+		events <- event
+	})
+
+	expect := func(eventType EventType, counter uint8, hasCounter bool) {
+		select {
+		case event := <-events:
+			if event.Type != eventType || event.Counter != counter || event.HasCounter != hasCounter {
+				t.Log("Unexpected event", event.Type, event.Counter, event.HasCounter)
+				t.FailNow()
+			}
+		case <-time.After(time.Second):
+			t.Log("Missing event", eventType)
+			t.FailNow()
+		}
+	}
+
+	if consumer.InWindow() {
+		t.Log("Inside the window before the first SYNC")
+		t.FailNow()
+	}
+
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeSync, []byte{3}).CANFrame())
+	expect(EventSync, 3, true)
+
+	if !consumer.InWindow() {
+		t.Log("Outside the window after SYNC")
+		t.FailNow()
+	}
+
+	// EMCY messages of nodes share the message type and are ignored
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeSync+5, []byte{0, 0, 0, 0, 0, 0, 0, 0}).CANFrame())
+
+	expect(EventMissing, 0, false)
+	if consumer.CheckWindow() != ErrOutsideWindow || consumer.Missed() != 1 {
+		t.Log("Unexpected window state", consumer.Missed())
+		t.FailNow()
+	}
+
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeSync, nil).CANFrame())
+	expect(EventSync, 0, false)
+
+	unsubscribe()
+	consumer.SetPeriod(0)
+	bus.PublishLocal(canopen.NewFrame(canopen.MessageTypeSync, nil).CANFrame())
+	select {
+	case event := <-events:
+		t.Log("Unexpected event after unsubscribe", event.Type)
+		t.FailNow()
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,214 @@
+package syncobj
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// MaxCounterOverflow is the largest valid synchronous counter overflow value (0x1019)
+const MaxCounterOverflow = 240
+
+// Statistics describes how accurate the SYNC messages were sent.
+type Statistics struct {
+	// Count is the number of sent SYNC messages
+	Count uint64
+	// Errors is the number of SYNC messages which could not be sent
+	Errors uint64
+	// MinPeriod, MaxPeriod and MeanPeriod are the measured intervals between two SYNC messages
+	MinPeriod  time.Duration
+	MaxPeriod  time.Duration
+	MeanPeriod time.Duration
+	// MaxJitter is the largest deviation of a measured interval from the communication cycle period
+	MaxJitter time.Duration
+}
+
+type statistics struct {
+	Statistics
+	total     time.Duration
+	intervals uint64
+	last      time.Time
+}
+
+func (stats *statistics) add(now time.Time, period time.Duration) {
+	stats.Count++
+	if !stats.last.IsZero() {
+		interval := now.Sub(stats.last)
+		if stats.intervals == 0 || interval < stats.MinPeriod {
+			stats.MinPeriod = interval
+		}
+		if interval > stats.MaxPeriod {
+			stats.MaxPeriod = interval
+		}
+
+		stats.intervals++
+		stats.total += interval
+		stats.MeanPeriod = stats.total / time.Duration(stats.intervals)
+
+		jitter := interval - period
+		if jitter < 0 {
+			jitter = -jitter
+		}
+		if jitter > stats.MaxJitter {
+			stats.MaxJitter = jitter
+		}
+	}
+
+	stats.last = now
+}
+
+// Producer publishes the SYNC message every communication cycle period (0x1006).
+type Producer struct {
+	// CobID is the COB-ID of the SYNC message (0x1005), 0 uses the default 0x080
+	CobID uint16
+	// Period is the communication cycle period (0x1006)
+	Period time.Duration
+	// CounterOverflow is the synchronous counter overflow value (0x1019).
+	// 0 sends SYNC messages without data, 2-240 adds a counter which restarts with 1 after the overflow value.
+	CounterOverflow uint8
+
+	bus *can.Bus
+
+	lock    sync.Mutex
+	counter uint8
+	stats   statistics
+	stop    chan struct{}
+}
+
+// Start starts publishing the SYNC message.
+func (producer *Producer) Start(bus *can.Bus) error {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	if producer.Period <= 0 {
+		return fmt.Errorf("invalid communication cycle period %s", producer.Period)
+	}
+
+	if producer.CounterOverflow == 1 || producer.CounterOverflow > MaxCounterOverflow {
+		return fmt.Errorf("invalid synchronous counter overflow value %d", producer.CounterOverflow)
+	}
+
+	producer.stopProducer()
+	producer.bus = bus
+	producer.counter = 1
+	producer.stats.last = time.Time{}
+
+	stop := make(chan struct{})
+	producer.stop = stop
+	go producer.run(producer.Period, stop)
+
+	return nil
+}
+
+// Stop stops publishing the SYNC message.
+func (producer *Producer) Stop() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.stopProducer()
+}
+
+// SetPeriod changes the communication cycle period, e.g. when 0x1006 is written.
+// A running producer restarts with the new period.
+func (producer *Producer) SetPeriod(period time.Duration) error {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	if period <= 0 {
+		return fmt.Errorf("invalid communication cycle period %s", period)
+	}
+
+	producer.Period = period
+	if producer.stop != nil {
+		producer.stopProducer()
+		producer.stats.last = time.Time{}
+
+		stop := make(chan struct{})
+		producer.stop = stop
+		go producer.run(period, stop)
+	}
+
+	return nil
+}
+
+// Running returns true while the SYNC message is published.
+func (producer *Producer) Running() bool {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	return producer.stop != nil
+}
+
+// Statistics returns the statistics since the last reset.
+func (producer *Producer) Statistics() Statistics {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	return producer.stats.Statistics
+}
+
+// ResetStatistics clears the statistics.
+func (producer *Producer) ResetStatistics() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.stats = statistics{}
+}
+
+// stopProducer stops the running producer, the lock must be held
+func (producer *Producer) stopProducer() {
+	if producer.stop != nil {
+		close(producer.stop)
+		producer.stop = nil
+	}
+}
+
+func (producer *Producer) run(period time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			producer.publish(period, stop)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (producer *Producer) publish(period time.Duration, stop chan struct{}) {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	// The producer may have been stopped while waiting for the lock
+	select {
+	case <-stop:
+		return
+	default:
+	}
+
+	cobID := producer.CobID
+	if cobID == 0 {
+		cobID = canopen.MessageTypeSync
+	}
+
+	var data []byte
+	if producer.CounterOverflow > 0 {
+		data = []byte{producer.counter}
+		producer.counter++
+		if producer.counter > producer.CounterOverflow {
+			producer.counter = 1
+		}
+	}
+
+	frame := canopen.NewFrame(cobID, data)
+	if err := producer.bus.PublishMinDuration(frame.CANFrame(), 0); err != nil {
+		producer.stats.Errors++
+		return
+	}
+
+	producer.stats.add(time.Now(), period)
+}
@@ -0,0 +1,63 @@
+package syncobj
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"testing"
+	"time"
+)
+
+func busPair() (*can.Bus, *can.Bus) {
+	a, b := net.Pipe()
+	busA := can.NewBus(can.NewReadWriteCloser(a), "a")
+	busB := can.NewBus(can.NewReadWriteCloser(b), "b")
+	go busA.ConnectAndPublish()
+	go busB.ConnectAndPublish()
+
+	return busA, busB
+}
+
+func TestProducer(t *testing.T) {
+	producerBus, consumerBus := busPair()
+	defer producerBus.Disconnect()
+
+	sub := canopen.Subscribe(consumerBus, canopen.MessageTypeSync, 10)
+	defer sub.Close()
+
+	producer := &Producer{Period: 10 * time.Millisecond, CounterOverflow: 3}
+	if err := producer.Start(producerBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, counter := range []uint8{1, 2, 3, 1, 2} {
+		frame, err := sub.Receive(time.Second)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if len(frame.Data) < 1 || frame.Data[0] != counter {
+			t.Log("Unexpected counter", frame.Data, "expected", counter)
+			t.FailNow()
+		}
+	}
+
+	producer.Stop()
+	if producer.Running() {
+		t.Log("Producer is still running")
+		t.FailNow()
+	}
+
+	stats := producer.Statistics()
+	if stats.Count < 5 || stats.Errors != 0 || stats.MinPeriod > stats.MeanPeriod || stats.MeanPeriod > stats.MaxPeriod {
+		t.Log("Unexpected statistics", stats)
+		t.FailNow()
+	}
+
+	if err := (&Producer{Period: time.Millisecond, CounterOverflow: 1}).Start(producerBus); err == nil {
+		t.Log("Expected error for invalid counter overflow value")
+		t.FailNow()
+	}
+}
@@ -1,7 +1,6 @@
 package canopen
 
 import (
-	"bytes"
 	"encoding/binary"
 	"fmt"
 	"time"
@@ -19,27 +18,103 @@ var RefDate = time.Date(
 	time.UTC, // location
 )
 
-// Timestamp returns the time encoded in the frame.
-func (frm Frame) Timestamp() (*time.Time, error) {
-	if t := frm.MessageType(); t != MessageTypeTimestamp {
-		return nil, fmt.Errorf("Invalid message type % X", t)
+// TimeOfDaySize is the number of bytes of the TIME_OF_DAY and TIME_DIFFERENCE data types.
+const TimeOfDaySize = 6
+
+const (
+	// maskMilliseconds is used to extract the 28-bit milliseconds after midnight
+	maskMilliseconds = 0x0FFFFFFF
+	day              = 24 * time.Hour
+	// maxTimeDifference is the largest duration which can be encoded with 16-bit days
+	maxTimeDifference = (0xFFFF+1)*day - time.Millisecond
+)
+
+// EncodeTimeOfDay returns the TIME_OF_DAY representation of t,
+// the milliseconds after midnight (28 bits) followed by the days since 1984-01-01 (16 bits).
+func EncodeTimeOfDay(t time.Time) ([]byte, error) {
+	if t.Before(RefDate) {
+		return nil, fmt.Errorf("time %s is before %s", t, RefDate)
 	}
 
-	if n := len(frm.Data); n != 8 {
-		return nil, fmt.Errorf("Invalid data length %d", n)
+	// Count whole days separately, the difference to the reference date may exceed time.Duration
+	days := (t.Unix() - RefDate.Unix()) / int64(day/time.Second)
+	if days > 0xFFFF {
+		return nil, fmt.Errorf("time %s exceeds the TIME_OF_DAY range", t)
 	}
 
-	var msec int32
-	var day int16
+	sinceMidnight := t.Sub(RefDate.AddDate(0, 0, int(days)))
+	return encodeTime(uint16(days), uint32(sinceMidnight/time.Millisecond)), nil
+}
+
+// DecodeTimeOfDay returns the time of a TIME_OF_DAY value.
+func DecodeTimeOfDay(data []byte) (time.Time, error) {
+	days, ms, err := decodeTime(data)
+	if err != nil {
+		return time.Time{}, err
+	}
 
-	msecBuf := bytes.NewBuffer(frm.Data[:6])
-	dayBuf := bytes.NewBuffer(frm.Data[6:8])
+	return RefDate.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+// EncodeTimeDifference returns the TIME_DIFFERENCE representation of d.
+func EncodeTimeDifference(d time.Duration) ([]byte, error) {
+	if d < 0 || d > maxTimeDifference {
+		return nil, fmt.Errorf("time difference %s out of range", d)
+	}
+
+	return encodeTime(uint16(d/day), uint32((d%day)/time.Millisecond)), nil
+}
+
+// DecodeTimeDifference returns the duration of a TIME_DIFFERENCE value.
+func DecodeTimeDifference(data []byte) (time.Duration, error) {
+	days, ms, err := decodeTime(data)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(days)*day + time.Duration(ms)*time.Millisecond, nil
+}
 
-	binary.Read(msecBuf, binary.LittleEndian, &msec)
-	binary.Read(dayBuf, binary.LittleEndian, &dayBuf)
+func encodeTime(days uint16, ms uint32) []byte {
+	data := make([]byte, TimeOfDaySize)
+	binary.LittleEndian.PutUint32(data[0:4], ms&maskMilliseconds)
+	binary.LittleEndian.PutUint16(data[4:6], days)
 
-	daySec := int64(day) * 24 * 60 * 60
-	t := time.Unix(RefDate.Unix()+daySec, int64(msec)*1000)
+	return data
+}
+
+func decodeTime(data []byte) (uint16, uint32, error) {
+	if n := len(data); n < TimeOfDaySize {
+		return 0, 0, fmt.Errorf("Invalid data length %d", n)
+	}
+
+	// The upper 4 bits of the milliseconds are reserved
+	ms := binary.LittleEndian.Uint32(data[0:4]) & maskMilliseconds
+	days := binary.LittleEndian.Uint16(data[4:6])
+
+	return days, ms, nil
+}
+
+// NewTimestampFrame returns a TIME message with the time t.
+func NewTimestampFrame(t time.Time) (Frame, error) {
+	data, err := EncodeTimeOfDay(t)
+	if err != nil {
+		return Frame{}, err
+	}
+
+	return NewFrame(MessageTypeTimestamp, data), nil
+}
+
+// Timestamp returns the time encoded in the frame.
+func (frm Frame) Timestamp() (*time.Time, error) {
+	if t := frm.MessageType(); t != MessageTypeTimestamp {
+		return nil, fmt.Errorf("Invalid message type % X", t)
+	}
+
+	t, err := DecodeTimeOfDay(frm.Data)
+	if err != nil {
+		return nil, err
+	}
 
 	return &t, nil
 }
@@ -0,0 +1,66 @@
+package canopen
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTimeOfDay(t *testing.T) {
+	date := time.Date(2023, 5, 17, 13, 45, 12, 345000000, time.UTC)
+	data, err := EncodeTimeOfDay(date)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// 2023-05-17 is day 14381 (0x382D) since 1984, 13:45:12.345 is 49512345 ms (0x02F37F99)
+	expected := []byte{0x99, 0x7F, 0xF3, 0x02, 0x2D, 0x38}
+	if !bytes.Equal(data, expected) {
+		t.Log("Unexpected data", data, "expected", expected)
+		t.FailNow()
+	}
+
+	frame := CANopenFrame(NewFrame(MessageTypeTimestamp, data).CANFrame())
+	decoded, err := frame.Timestamp()
+	if err != nil || !decoded.Equal(date) {
+		t.Log("Unexpected time", decoded, err)
+		t.FailNow()
+	}
+
+	// The reserved bits of the milliseconds are ignored
+	data[3] |= 0xF0
+	if decoded, err := DecodeTimeOfDay(data); err != nil || !decoded.Equal(date) {
+		t.Log("Unexpected time", decoded, err)
+		t.FailNow()
+	}
+
+	if _, err := EncodeTimeOfDay(RefDate.Add(-time.Millisecond)); err == nil {
+		t.Log("Expected error for a date before 1984")
+		t.FailNow()
+	}
+
+	if _, err := DecodeTimeOfDay(data[:5]); err == nil {
+		t.Log("Expected error for short data")
+		t.FailNow()
+	}
+}
+
+func TestTimeDifference(t *testing.T) {
+	difference := 3*24*time.Hour + 5*time.Second + 7*time.Millisecond
+	data, err := EncodeTimeDifference(difference)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if decoded, err := DecodeTimeDifference(data); err != nil || decoded != difference {
+		t.Log("Unexpected difference", decoded, err)
+		t.FailNow()
+	}
+
+	if _, err := EncodeTimeDifference(-time.Second); err == nil {
+		t.Log("Expected error for a negative difference")
+		t.FailNow()
+	}
+}
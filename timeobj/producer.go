@@ -0,0 +1,110 @@
+package timeobj
+
+import (
+	"fmt"
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"sync"
+	"time"
+)
+
+// Producer broadcasts the TIME message with the host clock so the nodes can synchronize their clocks.
+type Producer struct {
+	// CobID is the COB-ID of the TIME message (0x1012), 0 uses the default 0x100
+	CobID uint16
+	// Period is the interval of the TIME messages
+	Period time.Duration
+	// Clock returns the current time (optional), defaults to time.Now
+	Clock func() time.Time
+
+	bus *can.Bus
+
+	lock sync.Mutex
+	stop chan struct{}
+}
+
+// Start sends the TIME message immediately and afterwards every period.
+func (producer *Producer) Start(bus *can.Bus) error {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	if producer.Period <= 0 {
+		return fmt.Errorf("invalid period %s", producer.Period)
+	}
+
+	producer.stopProducer()
+	producer.bus = bus
+	if err := producer.publish(); err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	producer.stop = stop
+	go producer.run(producer.Period, stop)
+
+	return nil
+}
+
+// Stop stops sending the TIME message.
+func (producer *Producer) Stop() {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.stopProducer()
+}
+
+// Publish sends the TIME message once, e.g. after the host clock was adjusted.
+func (producer *Producer) Publish(bus *can.Bus) error {
+	producer.lock.Lock()
+	defer producer.lock.Unlock()
+
+	producer.bus = bus
+	return producer.publish()
+}
+
+// stopProducer stops the running producer, the lock must be held
+func (producer *Producer) stopProducer() {
+	if producer.stop != nil {
+		close(producer.stop)
+		producer.stop = nil
+	}
+}
+
+func (producer *Producer) run(period time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			producer.lock.Lock()
+			select {
+			case <-stop:
+			default:
+				_ = producer.publish()
+			}
+			producer.lock.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// publish sends the current time, the lock must be held
+func (producer *Producer) publish() error {
+	now := time.Now()
+	if producer.Clock != nil {
+		now = producer.Clock()
+	}
+
+	frame, err := canopen.NewTimestampFrame(now)
+	if err != nil {
+		return err
+	}
+
+	if producer.CobID != 0 {
+		frame.CobID = producer.CobID & canopen.MaskCobID
+	}
+
+	return producer.bus.PublishMinDuration(frame.CANFrame(), 0)
+}
@@ -0,0 +1,45 @@
+package timeobj
+
+import (
+	"github.com/FabianPetersen/can"
+	"github.com/FabianPetersen/canopen"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestProducer(t *testing.T) {
+	a, b := net.Pipe()
+	producerBus := can.NewBus(can.NewReadWriteCloser(a), "producer")
+	consumerBus := can.NewBus(can.NewReadWriteCloser(b), "consumer")
+	go producerBus.ConnectAndPublish()
+	go consumerBus.ConnectAndPublish()
+	defer producerBus.Disconnect()
+
+	sub := canopen.Subscribe(consumerBus, canopen.MessageTypeTimestamp, 10)
+	defer sub.Close()
+
+	now := time.Date(2023, 5, 17, 13, 45, 12, 345000000, time.UTC)
+	producer := &Producer{
+		Period: 10 * time.Millisecond,
+		Clock:  func() time.Time { return now },
+	}
+	if err := producer.Start(producerBus); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+	defer producer.Stop()
+
+	for i := 0; i < 3; i++ {
+		frame, err := sub.Receive(time.Second)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if timestamp, err := frame.Timestamp(); err != nil || !timestamp.Equal(now) {
+			t.Log("Unexpected timestamp", timestamp, err)
+			t.FailNow()
+		}
+	}
+}